@@ -0,0 +1,122 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"context"
+	"go/build"
+	"os"
+	"strings"
+
+	"github.com/andeya/aster/internal/buildutil"
+	"github.com/andeya/aster/internal/packagesdriver"
+)
+
+// SetBuildTags sets the build tags to consider satisfied during the build,
+// such as "linux integration".
+// The tags are parsed in the same manner as go build's -tags flag.
+func (prog *Program) SetBuildTags(tags string) (itself *Program) {
+	if prog.initiated || prog.initialError != nil {
+		return prog
+	}
+	var flag buildutil.TagsFlag
+	if err := flag.Set(strings.Replace(tags, ",", " ", -1)); err != nil {
+		prog.initialError = err
+		return prog
+	}
+	prog.buildTags = flag
+	prog.buildContext().BuildTags = flag
+	return prog
+}
+
+// SetGOOS sets the target operating system, such as "linux" and "windows".
+func (prog *Program) SetGOOS(goos string) (itself *Program) {
+	if !prog.initiated {
+		prog.buildContext().GOOS = goos
+		prog.setBuildEnv("GOOS", goos)
+	}
+	return prog
+}
+
+// SetGOARCH sets the target architecture, such as "amd64" and "386".
+// The sizes of types are computed for the target architecture.
+func (prog *Program) SetGOARCH(goarch string) (itself *Program) {
+	if !prog.initiated {
+		prog.buildContext().GOARCH = goarch
+		prog.setBuildEnv("GOARCH", goarch)
+	}
+	return prog
+}
+
+// SetCgoEnabled sets whether the cgo files are included, like CGO_ENABLED.
+func (prog *Program) SetCgoEnabled(enabled bool) (itself *Program) {
+	if !prog.initiated {
+		prog.buildContext().CgoEnabled = enabled
+		if enabled {
+			prog.setBuildEnv("CGO_ENABLED", "1")
+		} else {
+			prog.setBuildEnv("CGO_ENABLED", "0")
+		}
+	}
+	return prog
+}
+
+// buildContext returns the program's own build context, which is used to locate source packages.
+func (prog *Program) buildContext() *build.Context {
+	if prog.conf.Build == nil {
+		ctxt := build.Default // copy
+		prog.conf.Build = &ctxt
+	}
+	return prog.conf.Build
+}
+
+func (prog *Program) setBuildEnv(key, value string) {
+	for i, kv := range prog.buildEnv {
+		if strings.HasPrefix(kv, key+"=") {
+			prog.buildEnv[i] = key + "=" + value
+			return
+		}
+	}
+	prog.buildEnv = append(prog.buildEnv, key+"="+value)
+}
+
+// env returns the environment of the build system's query tool, or nil for the current one.
+func (prog *Program) env() []string {
+	if len(prog.buildEnv) == 0 {
+		return nil
+	}
+	return append(os.Environ(), prog.buildEnv...)
+}
+
+// buildFlags returns the flags of the build system's query tool.
+func (prog *Program) buildFlags() []string {
+	if len(prog.buildTags) == 0 {
+		return nil
+	}
+	return []string{"-tags=" + strings.Join(prog.buildTags, ",")}
+}
+
+// initSizes sets the sizes of types for the target, if the target is not the host.
+func (prog *Program) initSizes(ctx context.Context) error {
+	if prog.conf.TypeChecker.Sizes != nil || len(prog.buildEnv) == 0 {
+		return nil
+	}
+	sizes, err := packagesdriver.GetSizesGolist(ctx, prog.buildFlags(), prog.env(), prog.conf.Cwd, false)
+	if err != nil {
+		return err
+	}
+	prog.conf.TypeChecker.Sizes = sizes
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildContext(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":           "module example.com/m\n\ngo 1.18\n",
+		"p/p.go":           "package p\n\nimport \"unsafe\"\n\nconst IntSize = unsafe.Sizeof(int(0))\n",
+		"p/p_windows.go":   "package p\n\nfunc Windows() {}\n",
+		"p/p_linux.go":     "package p\n\nfunc Linux() {}\n",
+		"p/integration.go": "//go:build linux && integration\n\npackage p\n\nfunc Integration() {}\n",
+	})
+	has := func(prog *aster.Program, name string) bool {
+		return len(prog.InitialPackages()[0].Lookup(aster.Fun, 0, name)) > 0
+	}
+	for _, patterns := range []bool{false, true} {
+		newProg := func() *aster.Program {
+			prog := aster.NewProgram().SetWorkDir(dir)
+			if patterns {
+				return prog.ImportPatterns("./p")
+			}
+			return prog.Import("./p")
+		}
+		prog, err := newProg().SetGOOS("windows").SetGOARCH("386").Load()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.True(t, has(prog, "Windows"))
+		assert.False(t, has(prog, "Linux"))
+		assert.False(t, has(prog, "Integration"))
+		size := prog.InitialPackages()[0].Lookup(aster.Con, 0, "IntSize")[0]
+		assert.Equal(t, "4", size.Object().(*types.Const).Val().String())
+
+		prog, err = newProg().SetGOOS("linux").SetBuildTags("integration").Load()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.False(t, has(prog, "Windows"))
+		assert.True(t, has(prog, "Linux"))
+		assert.True(t, has(prog, "Integration"))
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/andeya/aster/internal/gcexportdata"
+)
+
+// SetCacheDir sets the directory of the persistent cache of the type-checked dependencies.
+// If empty, the cache is disabled.
+//
+// The dependencies are cached as export data, keyed by the hash of their source files
+// and the ones of their dependencies, the build tags, GOOS, GOARCH, CGO_ENABLED and Go version,
+// so that a change in a dependency invalidates it and all the packages depending on it.
+// NOTE:
+//
+//	Only the dependencies loaded by the GOPATH loader are cached, see SetDepsFromExportData for the module mode;
+//	The cgo packages and the packages with errors are not cached.
+func (prog *Program) SetCacheDir(dir string) (itself *Program) {
+	if !prog.initiated {
+		prog.cacheDir = dir
+	}
+	return prog
+}
+
+// diskCache implements loader.PackageCache, storing the export data in a directory.
+type diskCache struct {
+	dir  string
+	fset *token.FileSet
+	ctxt *build.Context
+
+	mu   sync.Mutex
+	keys map[string]string // <import path, key>
+}
+
+func newDiskCache(prog *Program) *diskCache {
+	return &diskCache{
+		dir:  prog.cacheDir,
+		fset: prog.conf.Fset,
+		ctxt: prog.buildContext(),
+		keys: make(map[string]string),
+	}
+}
+
+// Get implements loader.PackageCache.
+func (c *diskCache) Get(bp *build.Package, imports map[string]*types.Package) *types.Package {
+	key, err := c.key(bp, nil)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(c.filename(key))
+	if err != nil {
+		return nil
+	}
+	pkg, err := gcexportdata.Read(bytes.NewReader(data), c.fset, imports, bp.ImportPath)
+	if err != nil {
+		return nil
+	}
+	return pkg
+}
+
+// Put implements loader.PackageCache.
+func (c *diskCache) Put(bp *build.Package, pkg *types.Package) {
+	key, err := c.key(bp, nil)
+	if err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err = gcexportdata.Write(&buf, c.fset, pkg); err != nil {
+		return
+	}
+	if err = os.MkdirAll(c.dir, 0777); err != nil {
+		return
+	}
+	// Write to a temporary file and rename it,
+	// so that the concurrent processes never read a partial file.
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.filename(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (c *diskCache) filename(key string) string {
+	return filepath.Join(c.dir, key+".export")
+}
+
+// key returns the cache key of the package, computed from its source files
+// and the keys of its imports.
+// visiting contains the import paths being computed, for detecting the cycles.
+func (c *diskCache) key(bp *build.Package, visiting map[string]bool) (string, error) {
+	c.mu.Lock()
+	key, ok := c.keys[bp.ImportPath]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if visiting[bp.ImportPath] {
+		return "", fmt.Errorf("import cycle through %s", bp.ImportPath)
+	}
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	visiting[bp.ImportPath] = true
+	defer delete(visiting, bp.ImportPath)
+
+	h := sha256.New()
+	ctxt := c.ctxt
+	fmt.Fprintf(h, "go %s %s/%s cgo=%t tags=%q\n", runtime.Version(), ctxt.GOOS, ctxt.GOARCH, ctxt.CgoEnabled, ctxt.BuildTags)
+	fmt.Fprintf(h, "package %s %s\n", bp.ImportPath, bp.Name)
+	files := append(append([]string(nil), bp.GoFiles...), bp.CgoFiles...)
+	sort.Strings(files)
+	for _, name := range files {
+		if err := c.hashFile(h, filepath.Join(bp.Dir, name)); err != nil {
+			return "", err
+		}
+	}
+	paths := append([]string(nil), bp.Imports...)
+	sort.Strings(paths)
+	for _, path := range paths {
+		if path == "C" || path == "unsafe" {
+			fmt.Fprintf(h, "import %s\n", path)
+			continue
+		}
+		dep, err := ctxt.Import(path, bp.Dir, 0)
+		if err != nil {
+			return "", err
+		}
+		depKey, err := c.key(dep, visiting)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "import %s %s\n", dep.ImportPath, depKey)
+	}
+	key = hex.EncodeToString(h.Sum(nil))
+
+	c.mu.Lock()
+	c.keys[bp.ImportPath] = key
+	c.mu.Unlock()
+	return key, nil
+}
+
+// hashFile writes the name and contents of the file to the hash.
+func (c *diskCache) hashFile(h hash.Hash, filename string) error {
+	var (
+		rc  io.ReadCloser
+		err error
+	)
+	if c.ctxt.OpenFile != nil {
+		rc, err = c.ctxt.OpenFile(filename)
+	} else {
+		rc, err = os.Open(filename)
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "file %s %d\n", filepath.Base(filename), len(content))
+	h.Write(content)
+	return nil
+}
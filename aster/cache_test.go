@@ -0,0 +1,129 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheDir(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.18\n",
+		"a/a.go":  "package a\n\nimport (\n\t\"example.com/m/b\"\n\t\"io\"\n)\n\ntype A struct {\n\tB b.B\n\tR io.Reader\n}\n",
+		"b/b.go":  "package b\n\ntype B struct{ N int }\n",
+		"main.go": "package main\n\nimport (\n\t\"example.com/m/a\"\n\t\"io\"\n)\n\nvar X = a.A{}.B.N\n\nvar _ io.Reader = a.A{}.R\n",
+	})
+	cacheDir := t.TempDir()
+	load := func() (checked []string) {
+		prog, err := aster.NewProgram().
+			SetWorkDir(dir).
+			SetCacheDir(cacheDir).
+			SetProgress(func(p aster.Progress) {
+				if p.Event == aster.PackageStarted && filepath.Dir(p.Path) == "example.com/m" {
+					checked = append(checked, p.Path)
+				}
+			}).
+			AddFile(filepath.Join(dir, "main.go"), nil).
+			Load()
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		assert.Empty(t, prog.Diagnostics())
+		x := prog.InitialPackages()[0].Lookup(aster.Var, 0, "X")
+		if assert.Len(t, x, 1) {
+			assert.Equal(t, "int", x[0].Object().Type().String())
+		}
+		sort.Strings(checked)
+		return checked
+	}
+
+	assert.Equal(t, []string{"example.com/m/a", "example.com/m/b"}, load())
+	assert.Empty(t, load())
+
+	// A change in a dependency invalidates it and the packages depending on it.
+	err := os.WriteFile(filepath.Join(dir, "b/b.go"), []byte("package b\n\ntype B struct{ N int; M int }\n"), 0666)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"example.com/m/a", "example.com/m/b"}, load())
+		assert.Empty(t, load())
+	}
+}
+
+func TestCacheDirGeneric(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"g/g.go": `package g
+
+import "sync/atomic"
+
+type Number interface {
+	~int | ~float64
+}
+
+type List[T any] struct {
+	items []T
+	last  atomic.Pointer[T]
+}
+
+func (l *List[T]) Push(v T) { l.items = append(l.items, v); l.last.Store(&v) }
+
+func (l *List[T]) Items() []T { return l.items }
+
+type Ints = List[int]
+
+func Sum[T Number](l *List[T]) (s T) {
+	for _, v := range l.Items() {
+		s += v
+	}
+	return
+}
+`,
+		"main.go": "package main\n\nimport \"example.com/m/g\"\n\nvar L g.Ints\n\nvar X = g.Sum(&L)\n\nvar Y = g.Sum(&g.List[float64]{})\n",
+	})
+	cacheDir := t.TempDir()
+	load := func() (checked []string) {
+		prog, err := aster.NewProgram().
+			SetWorkDir(dir).
+			SetCacheDir(cacheDir).
+			SetProgress(func(p aster.Progress) {
+				if p.Event == aster.PackageStarted && (p.Path == "example.com/m/g" || p.Path == "sync/atomic") {
+					checked = append(checked, p.Path)
+				}
+			}).
+			AddFile(filepath.Join(dir, "main.go"), nil).
+			Load()
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		assert.Empty(t, prog.Diagnostics())
+		pkg := prog.InitialPackages()[0]
+		for name, typ := range map[string]string{"X": "int", "Y": "float64", "L": "example.com/m/g.Ints"} {
+			obj := pkg.Lookup(aster.Var, 0, name)
+			if assert.Len(t, obj, 1) {
+				assert.Equal(t, typ, obj[0].Object().Type().String())
+			}
+		}
+		sort.Strings(checked)
+		return checked
+	}
+
+	assert.Equal(t, []string{"example.com/m/g", "sync/atomic"}, load())
+	assert.Empty(t, load())
+}
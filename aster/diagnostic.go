@@ -0,0 +1,249 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andeya/aster/internal/packages"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	// Hard errors make the program incorrect, such as syntax errors and undefined identifiers.
+	Hard Severity = iota
+	// Soft errors are reported by go/types but not by gc, such as unused variables and imports,
+	// the type information is still complete.
+	Soft
+)
+
+// String returns the severity name.
+func (s Severity) String() string {
+	switch s {
+	case Hard:
+		return "hard"
+	case Soft:
+		return "soft"
+	}
+	return "Severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// DiagnosticSource is the loading stage that reported a diagnostic.
+type DiagnosticSource int
+
+const (
+	// LoaderSource is the locating and loading of the packages.
+	LoaderSource DiagnosticSource = iota
+	// ParseSource is the parser.
+	ParseSource
+	// TypeCheckSource is the type checker.
+	TypeCheckSource
+)
+
+// String returns the source name.
+func (s DiagnosticSource) String() string {
+	switch s {
+	case LoaderSource:
+		return "loader"
+	case ParseSource:
+		return "parse"
+	case TypeCheckSource:
+		return "type-check"
+	}
+	return "DiagnosticSource(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Diagnostic is a problem found when loading the program.
+type Diagnostic struct {
+	Package  *PackageInfo   // the package having the problem
+	Position token.Position // invalid if unknown
+	Severity Severity
+	Source   DiagnosticSource
+	Message  string
+	Err      error // the original error, nil if reported by aster
+}
+
+// Error returns the diagnostic in the form of "file:line:col: message".
+func (d Diagnostic) Error() string {
+	return d.Position.String() + ": " + d.Message
+}
+
+// Diagnostics returns the diagnostics of all the packages in the program,
+// including the dependencies, ordered by package path.
+func (prog *Program) Diagnostics() []Diagnostic {
+	pkgs := make([]*PackageInfo, 0, len(prog.allPackages))
+	for _, pkg := range prog.allPackages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		return pkgs[i].Pkg.Path() < pkgs[j].Pkg.Path()
+	})
+	var diags []Diagnostic
+	for _, pkg := range pkgs {
+		diags = append(diags, pkg.Diagnostics()...)
+	}
+	return diags
+}
+
+// Diagnostics returns the diagnostics of the package, ordered by position.
+func (p *PackageInfo) Diagnostics() []Diagnostic {
+	var diags []Diagnostic
+	for _, err := range p.Errors {
+		diags = append(diags, p.newDiagnostics(err)...)
+	}
+	diags = append(diags, p.checkErrors...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Position, diags[j].Position
+		if a.IsValid() != b.IsValid() {
+			return a.IsValid() // unknown positions last
+		}
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diags
+}
+
+// newDiagnostics converts the error reported by the loader, parser or type checker.
+func (p *PackageInfo) newDiagnostics(err error) []Diagnostic {
+	switch e := err.(type) {
+	case types.Error:
+		severity := Hard
+		if e.Soft {
+			severity = Soft
+		}
+		return []Diagnostic{{
+			Package:  p,
+			Position: e.Fset.Position(e.Pos),
+			Severity: severity,
+			Source:   TypeCheckSource,
+			Message:  e.Msg,
+			Err:      err,
+		}}
+	case scanner.ErrorList:
+		diags := make([]Diagnostic, 0, len(e))
+		for _, se := range e {
+			diags = append(diags, p.newDiagnostics(*se)...)
+		}
+		return diags
+	case scanner.Error:
+		return []Diagnostic{{
+			Package:  p,
+			Position: e.Pos,
+			Severity: Hard,
+			Source:   ParseSource,
+			Message:  e.Msg,
+			Err:      err,
+		}}
+	case packages.Error:
+		source := LoaderSource
+		switch e.Kind {
+		case packages.ParseError:
+			source = ParseSource
+		case packages.TypeError:
+			source = TypeCheckSource
+		}
+		return []Diagnostic{{
+			Package:  p,
+			Position: parsePosition(e.Pos),
+			Severity: Hard,
+			Source:   source,
+			Message:  e.Msg,
+			Err:      err,
+		}}
+	}
+	return []Diagnostic{{
+		Package:  p,
+		Severity: Hard,
+		Source:   LoaderSource,
+		Message:  err.Error(),
+		Err:      err,
+	}}
+}
+
+// parsePosition parses the position in the form of "file:line:col", "file:line", "" or "-".
+func parsePosition(s string) (pos token.Position) {
+	if s == "" || s == "-" {
+		return
+	}
+	pos.Filename = s
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndexByte(pos.Filename, ':')
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(pos.Filename[i+1:])
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+		pos.Filename = pos.Filename[:i]
+	}
+	switch len(nums) {
+	case 1:
+		pos.Line = nums[0]
+	case 2:
+		pos.Line, pos.Column = nums[1], nums[0]
+	}
+	return
+}
+
+// SetStrict sets whether Load fails when any package has hard errors.
+// By default, Load allows the errors, which can be obtained by Diagnostics.
+func (prog *Program) SetStrict(strict bool) (itself *Program) {
+	if !prog.initiated {
+		prog.strict = strict
+	}
+	return prog
+}
+
+// checkStrict returns an error if any package has hard errors in strict mode.
+func (prog *Program) checkStrict() error {
+	if !prog.strict {
+		return nil
+	}
+	var errpkgs []string
+	for _, info := range prog.allPackages {
+		for _, d := range info.Diagnostics() {
+			if d.Severity == Hard {
+				errpkgs = append(errpkgs, info.Pkg.Path())
+				break
+			}
+		}
+	}
+	if errpkgs == nil {
+		return nil
+	}
+	sort.Strings(errpkgs)
+	var more string
+	if len(errpkgs) > 3 {
+		more = fmt.Sprintf(" and %d more", len(errpkgs)-3)
+		errpkgs = errpkgs[:3]
+	}
+	return fmt.Errorf("couldn't load packages due to errors: %s%s", strings.Join(errpkgs, ", "), more)
+}
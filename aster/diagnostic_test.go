@@ -0,0 +1,104 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	var src = `package test
+func F() {
+	var unused int
+}
+var X = Undefined
+`
+	prog, err := aster.LoadFile("diagnostics.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	diags := prog.Diagnostics()
+	if assert.Len(t, diags, 2) {
+		assert.Equal(t, aster.TypeCheckSource, diags[0].Source)
+		assert.Equal(t, aster.Soft, diags[0].Severity)
+		assert.Equal(t, "diagnostics.go:3:6: declared and not used: unused", diags[0].Error())
+		assert.Equal(t, "test", diags[0].Package.Pkg.Path())
+		assert.Equal(t, aster.TypeCheckSource, diags[1].Source)
+		assert.Equal(t, aster.Hard, diags[1].Severity)
+		assert.Equal(t, "diagnostics.go:5:9: undefined: Undefined", diags[1].Error())
+	}
+
+	_, err = aster.NewProgram().SetStrict(true).AddFile("diagnostics.go", src).Load()
+	assert.EqualError(t, err, "couldn't load packages due to errors: test")
+	_, err = aster.NewProgram().SetStrict(true).AddFile("diagnostics.go", "package test\nimport \"fmt\"\n").Load()
+	assert.NoError(t, err)
+}
+
+func TestDiagnosticsPatterns(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nfunc A() int {\n",
+		"b/b.go": "package b\n\nimport \"example.com/m/a\"\n\nvar B int = a.A()\n\nfunc F() { var unused int }\n",
+	})
+	prog, err := aster.NewProgram().SetWorkDir(dir).ImportPatterns("./b").Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var sources = make(map[string][]aster.DiagnosticSource)
+	var severities = make(map[string][]aster.Severity)
+	for _, d := range prog.Diagnostics() {
+		sources[d.Package.Pkg.Path()] = append(sources[d.Package.Pkg.Path()], d.Source)
+		severities[d.Package.Pkg.Path()] = append(severities[d.Package.Pkg.Path()], d.Severity)
+	}
+	if assert.NotEmpty(t, sources["example.com/m/a"]) {
+		assert.Equal(t, aster.ParseSource, sources["example.com/m/a"][0])
+	}
+	assert.Equal(t, []aster.DiagnosticSource{aster.TypeCheckSource}, sources["example.com/m/b"])
+	assert.Equal(t, []aster.Severity{aster.Soft}, severities["example.com/m/b"])
+
+	_, err = aster.NewProgram().SetWorkDir(dir).SetStrict(true).ImportPatterns("./b").Load()
+	assert.EqualError(t, err, "couldn't load packages due to errors: example.com/m/a")
+}
+
+func TestDiagnosticsPartialFile(t *testing.T) {
+	var src = `package test
+type A struct{ X int }
+var B = A{}
+func F() {
+	if
+}
+`
+	prog, err := aster.LoadFile("partial.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, prog.Lookup(aster.Typ, aster.Struct, "A"), 1)
+	assert.Len(t, prog.Lookup(aster.Fun, 0, "F"), 1)
+	assert.Len(t, prog.Lookup(aster.Var, 0, "B"), 1)
+	diags := prog.Diagnostics()
+	if assert.NotEmpty(t, diags) {
+		assert.Equal(t, aster.ParseSource, diags[0].Source)
+		assert.Equal(t, aster.Hard, diags[0].Severity)
+		assert.Equal(t, "partial.go", diags[0].Position.Filename)
+	}
+
+	_, err = aster.NewProgram().SetStrict(true).AddFile("partial.go", src).Load()
+	assert.EqualError(t, err, "couldn't load packages due to errors: test")
+	_, err = aster.LoadFile("partial.go", "packag test\n")
+	assert.Error(t, err)
+}
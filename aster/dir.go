@@ -0,0 +1,208 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andeya/aster/internal/buildutil"
+	"github.com/andeya/aster/internal/imports"
+	"github.com/andeya/aster/internal/loader"
+)
+
+// AddDirs parses the source code of Go packages under the directories recursively.
+//
+// The files of each directory are grouped into one created package,
+// whose path is the import path of the directory in its module or GOPATH,
+// or else its package name.
+// Like the go command, it skips the directories named testdata or vendor,
+// or beginning with "." or "_", the files beginning with "." or "_",
+// the *_test.go files and the files excluded by the build constraints.
+func (prog *Program) AddDirs(dirs ...string) (itself *Program) {
+	return prog.addDirs(dirs, false)
+}
+
+// AddDirsWithTests is the same as AddDirs, but also parses the *_test.go files.
+//
+// The package of each directory will be augmented by the *_test.go files
+// that contain a "package x" (not "package x_test") declaration.
+//
+// In addition, if any *_test.go files contain a "package x_test"
+// declaration, an additional package comprising just those files will
+// be created.
+func (prog *Program) AddDirsWithTests(dirs ...string) (itself *Program) {
+	return prog.addDirs(dirs, true)
+}
+
+func (prog *Program) addDirs(dirs []string, tests bool) (itself *Program) {
+	if prog.initiated || prog.initialError != nil {
+		return prog
+	}
+	for _, dir := range dirs {
+		if prog.fsys != nil {
+			dir = prog.fsPath(dir)
+		} else if !filepath.IsAbs(dir) {
+			dir, _ = filepath.Abs(dir)
+		}
+		if err := prog.walkDir(dir, tests); err != nil {
+			prog.initialError = err
+			break
+		}
+	}
+	return prog
+}
+
+// walkDir adds the packages of the directory and its subdirectories,
+// reading them through the build context.
+func (prog *Program) walkDir(dir string, tests bool) error {
+	if err := prog.addDir(dir, tests); err != nil {
+		return err
+	}
+	infos, err := buildutil.ReadDir(prog.buildContext(), dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	for _, info := range infos {
+		if info.IsDir() && !skipDir(info.Name()) {
+			if err = prog.walkDir(filepath.Join(dir, info.Name()), tests); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipDir reports whether the directory is ignored by the go command.
+func skipDir(name string) bool {
+	return name == "testdata" || name == "vendor" ||
+		strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// addDir creates the package of the directory, and the external test package if any.
+func (prog *Program) addDir(dir string, tests bool) error {
+	entries, err := buildutil.ReadDir(prog.buildContext(), dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if !tests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := prog.buildContext().MatchFile(dir, name); err != nil {
+			return err
+		} else if match {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var (
+		pkgName, firstName string
+		files, xtestFiles  []*loader.File
+	)
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		f, filename, ok := prog.parseFile(filename, nil)
+		if !ok {
+			return prog.initialError
+		}
+		file := &loader.File{Filename: filename, File: f}
+		isTest := strings.HasSuffix(name, "_test.go")
+		if isTest && strings.HasSuffix(f.Name.Name, "_test") {
+			xtestFiles = append(xtestFiles, file)
+			continue
+		}
+		switch pkgName {
+		case "":
+			pkgName, firstName = f.Name.Name, name
+		case f.Name.Name:
+		default:
+			return fmt.Errorf("found packages %s (%s) and %s (%s) in %s",
+				pkgName, firstName, f.Name.Name, name, dir)
+		}
+		files = append(files, file)
+	}
+	// The package is importable by its import path, so that it is shared
+	// with the external test package and the other packages importing it.
+	pkgPath := prog.importPath(dir)
+	if len(files) > 0 {
+		if pkgPath != "" {
+			prog.conf.CreateImportableFromFiles(pkgPath, files...)
+		} else {
+			prog.conf.CreateFromFiles(pkgName, files...)
+		}
+	}
+	if len(xtestFiles) > 0 {
+		if pkgPath != "" {
+			prog.conf.CreateFromFiles(pkgPath+"_test", xtestFiles...)
+		} else {
+			prog.conf.CreateFromFiles(xtestFiles[0].Name.Name, xtestFiles...)
+		}
+	}
+	return nil
+}
+
+// importPath returns the import path of the directory, relative to the root of its module,
+// or else to the source root of a GOPATH, or "" if it is in neither of them.
+func (prog *Program) importPath(dir string) string {
+	ctxt := prog.buildContext()
+	for root := dir; ; {
+		if modPath := prog.modulePath(filepath.Join(root, "go.mod")); modPath != "" {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return ""
+			}
+			return path.Join(modPath, filepath.ToSlash(rel))
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			break
+		}
+		root = parent
+	}
+	for _, gopath := range filepath.SplitList(ctxt.GOPATH) {
+		rel, err := filepath.Rel(filepath.Join(gopath, "src"), dir)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return ""
+}
+
+// modulePath returns the module path declared by the go.mod file, or "" if it is not one.
+func (prog *Program) modulePath(gomod string) string {
+	rc, err := buildutil.OpenFile(prog.buildContext(), gomod)
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return ""
+	}
+	return imports.ModulePath(data)
+}
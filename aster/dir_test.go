@@ -0,0 +1,107 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"go/types"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDirs(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"a.go":           "package p\n\ntype A struct{ B B }\n",
+		"b.go":           "package p\n\ntype B int\n",
+		"ignored.go":     "//go:build ignore\n\npackage main\n",
+		"a_test.go":      "package p\n\nvar testA = A{}\n",
+		"x_test.go":      "package p_test\n\nvar X = 1\n",
+		"sub/c.go":       "package sub\n\nconst C = 1\n",
+		"testdata/t.go":  "package t\n",
+		"vendor/v/v.go":  "package v\n",
+		"_skip/s.go":     "package s\n",
+		".hidden/h.go":   "package h\n",
+		"sub/_tmp.go":    "package tmp\n",
+		"sub/.c_copy.go": "package tmp\n",
+	})
+	pkgFiles := func(prog *aster.Program) map[string][]string {
+		m := make(map[string][]string)
+		for _, pkg := range prog.InitialPackages() {
+			assert.Empty(t, pkg.Errors, pkg.Pkg.Path())
+			for _, f := range pkg.Files {
+				rel, _ := filepath.Rel(dir, f.Filename)
+				m[pkg.Pkg.Path()] = append(m[pkg.Pkg.Path()], filepath.ToSlash(rel))
+			}
+			sort.Strings(m[pkg.Pkg.Path()])
+		}
+		return m
+	}
+
+	prog, err := aster.LoadDirs(dir)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string][]string{
+			"p":   {"a.go", "b.go"},
+			"sub": {"sub/c.go"},
+		}, pkgFiles(prog))
+	}
+
+	prog, err = aster.LoadDirsWithTests(dir)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string][]string{
+			"p":      {"a.go", "a_test.go", "b.go"},
+			"p_test": {"x_test.go"},
+			"sub":    {"sub/c.go"},
+		}, pkgFiles(prog))
+	}
+
+	dir = writeModule(t, map[string]string{
+		"a.go": "package a\n",
+		"b.go": "package b\n",
+	})
+	_, err = aster.LoadDirs(dir)
+	assert.EqualError(t, err, "found packages a (a.go) and b (b.go) in "+dir)
+}
+
+func TestLoadDirsImportPath(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":           "module example.com/m\n\ngo 1.18\n",
+		"a/util/util.go":   "package util\n\ntype T struct{ A int }\n",
+		"a/util/x_test.go": "package util_test\n\nimport \"example.com/m/a/util\"\n\nvar X util.T\n",
+		"b/util/util.go":   "package util\n\nconst B = 1\n",
+		"c/c.go":           "package c\n\nimport \"example.com/m/a/util\"\n\nvar C util.T\n",
+	})
+	prog, err := aster.LoadDirsWithTests(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var paths []string
+	for _, pkg := range prog.InitialPackages() {
+		assert.Empty(t, pkg.Errors, pkg.Pkg.Path())
+		paths = append(paths, pkg.Pkg.Path())
+	}
+	sort.Strings(paths)
+	assert.Equal(t, []string{"example.com/m/a/util", "example.com/m/a/util_test", "example.com/m/b/util", "example.com/m/c"}, paths)
+
+	// The external test package and the other packages import the created package under test.
+	util := prog.Package("example.com/m/a/util")
+	typ := util.Lookup(aster.Typ, aster.Struct, "T")[0].Object().Type()
+	x := prog.Package("example.com/m/a/util_test").Lookup(aster.Var, aster.Struct, "X")[0]
+	c := prog.Package("example.com/m/c").Lookup(aster.Var, aster.Struct, "C")[0]
+	assert.True(t, types.Identical(typ, x.Object().Type()))
+	assert.True(t, types.Identical(typ, c.Object().Type()))
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/andeya/aster/internal/gcexportdata"
+	"github.com/andeya/aster/internal/packages"
+)
+
+// SetDepsFromExportData sets whether the dependencies are loaded from the compiler export data
+// instead of source, so that only the created and imported packages are parsed.
+//
+// The export data is built by 'go list -export' in the program's working directory,
+// using the build cache of the go command.
+// NOTE:
+//
+//	The dependencies have no files, and their facades have no syntax;
+//	A dependency whose export data can not be built is loaded from source.
+func (prog *Program) SetDepsFromExportData(enabled bool) (itself *Program) {
+	if !prog.initiated {
+		prog.depsFromExportData = enabled
+	}
+	return prog
+}
+
+// exportImporter imports the packages from the compiler export data,
+// located by 'go list -export' on demand.
+type exportImporter struct {
+	prog  *Program
+	ctx   context.Context
+	mu    sync.Mutex
+	imp   types.Importer
+	files map[string]string // <package path, export data file>, "" if not found
+}
+
+func newExportImporter(ctx context.Context, prog *Program) *exportImporter {
+	e := &exportImporter{
+		prog:  prog,
+		ctx:   ctx,
+		files: make(map[string]string),
+	}
+	e.imp = gcexportdata.NewLookupImporter(prog.conf.Fset, e.lookup)
+	return e
+}
+
+// importFrom implements loader.Config.ImportFromExportData.
+func (e *exportImporter) importFrom(path, _ string) (*types.Package, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.imp.Import(path)
+}
+
+// lookup opens the export data file of the package path.
+func (e *exportImporter) lookup(path string) (io.ReadCloser, error) {
+	file, ok := e.files[path]
+	if !ok {
+		if err := e.list(path); err != nil {
+			return nil, err
+		}
+		file = e.files[path]
+	}
+	if file == "" {
+		return nil, fmt.Errorf("no export data for %s", path)
+	}
+	return os.Open(file)
+}
+
+// list builds the export data of the package and its dependencies.
+func (e *exportImporter) list(path string) error {
+	e.files[path] = ""
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:       packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedExportsFile,
+		Context:    e.ctx,
+		Dir:        e.prog.conf.Cwd,
+		Env:        e.prog.env(),
+		BuildFlags: e.prog.buildFlags(),
+	}, path)
+	if err != nil {
+		return err
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.ExportFile != "" {
+			if _, ok := e.files[pkg.PkgPath]; !ok || pkg.PkgPath == path {
+				e.files[pkg.PkgPath] = pkg.ExportFile
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepsFromExportData(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nimport \"strings\"\n\ntype Builder = strings.Builder\n\nfunc Max[T int | string](x, y T) T {\n\tif x > y {\n\t\treturn x\n\t}\n\treturn y\n}\n",
+		"b/b.go": "package b\n\nimport (\n\t\"example.com/m/a\"\n\t\"strings\"\n)\n\nvar B a.Builder = strings.Builder{}\n\nvar M = a.Max(1, 2)\n",
+	})
+	var src = "package c\n\nimport \"strings\"\n\nvar M = strings.Builder{}\n"
+	for _, prog := range []*aster.Program{
+		aster.NewProgram().SetWorkDir(dir).ImportPatterns("./b"),
+		aster.NewProgram().AddFile("exportdata.go", src),
+	} {
+		var parsed []string
+		prog.SetProgress(func(p aster.Progress) {
+			if p.Event == aster.PackageStarted {
+				parsed = append(parsed, p.Path)
+			}
+		})
+		_, err := prog.SetDepsFromExportData(true).Load()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Len(t, parsed, 1)
+		assert.Empty(t, prog.Diagnostics())
+		b := prog.InitialPackages()[0]
+		m := b.Lookup(aster.Var, 0, "M")
+		if assert.Len(t, m, 1) {
+			assert.Contains(t, []string{"int", "strings.Builder"}, m[0].Object().Type().String())
+		}
+	}
+}
@@ -15,7 +15,6 @@
 package aster
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
@@ -61,6 +60,16 @@ type Facade interface {
 	// NOTE: If the type is *type.Named, returns the underlying TypKind.
 	TypKind() TypKind
 
+	// IsInvalid reports whether the type is, or is composed of, a type that can not be resolved,
+	// such as an undefined identifier or an unresolved import.
+	IsInvalid() bool
+
+	// TypeExpr returns the type expression written in the source,
+	// such as the type of variable, the type definition, the function signature
+	// or the constraint of type parameter.
+	// NOTE: Returns "", if the type is omitted (e.g. v := 1) or the syntax is unavailable.
+	TypeExpr() string
+
 	// Id is a wrapper for Id(obj.Pkg(), obj.Name()).
 	Id() string
 
@@ -82,6 +91,43 @@ type Facade interface {
 	// String previews the object formated code and comment.
 	String() string
 
+	// Parent returns the innermost facade whose declaration encloses the declaration,
+	// such as the function of a local type, or nil if none.
+	Parent() Facade
+
+	// Children returns the facades whose Parent is it, in the source order.
+	Children() []Facade
+
+	// Scope returns the scope in which the object is declared,
+	// or nil for a method, a struct field or an interface method.
+	Scope() *types.Scope
+
+	// ScopeKind returns where the object is declared.
+	ScopeKind() ScopeKind
+
+	// IsPackageLevel reports whether the object is declared at package level, including the methods.
+	IsPackageLevel() bool
+
+	// IsLocal reports whether the object is local to some function.
+	IsLocal() bool
+
+	// Position returns the position of the declared name.
+	Position() token.Position
+
+	// Range returns the start and end positions of the declaration, including its doc comment.
+	Range() (start, end token.Position)
+
+	// Source returns the original source code of the declaration, including its doc comment.
+	Source() ([]byte, error)
+
+	// References returns the uses of the object across all the loaded packages,
+	// ordered by filename and position.
+	References() []Reference
+
+	// Rename renames the declaration and all its references across the program,
+	// and returns an error without changing anything if it would break the program.
+	Rename(newName string) error
+
 	// Underlying returns the underlying type of a type.
 	Underlying() types.Type
 
@@ -95,6 +141,11 @@ type Facade interface {
 	// NOTE: the result's TypKind is Signature.
 	Method(i int) Facade
 
+	// MethodSet returns the method set of the type, or of its pointer type if ptr is true,
+	// including the methods promoted from the embedded fields, ordered by their unique Id.
+	// NOTE: Returns nil, if ObjKind != Typ.
+	MethodSet(ptr bool) []MethodSetEntry
+
 	// AssertableTo reports whether it can be asserted to have T's type.
 	AssertableTo(T Facade) bool
 
@@ -104,10 +155,61 @@ type Facade interface {
 	// ConvertibleTo reports whether it is convertible to a value of T's type.
 	ConvertibleTo(T Facade) bool
 
+	// ExplainAssignableTo returns why it is not assignable to a variable of T's type,
+	// or nil if AssignableTo returns true.
+	ExplainAssignableTo(T Facade) *Mismatch
+
+	// ExplainConvertibleTo returns why it is not convertible to a value of T's type,
+	// or nil if ConvertibleTo returns true.
+	ExplainConvertibleTo(T Facade) *Mismatch
+
 	// Implements reports whether it implements iface.
 	// NOTE: Panic, if iface TypKind != Interface
 	Implements(iface Facade, usePtr bool) bool
 
+	// ExplainImplements returns why it does not implement iface,
+	// or nil if Implements returns true.
+	// NOTE: Panic, if iface TypKind != Interface
+	ExplainImplements(iface Facade, usePtr bool) *Mismatch
+
+	// SatisfiedInterfaces returns the named interfaces in the program satisfied by the type
+	// or its pointer type, except the empty ones, ordered by package path and name.
+	// NOTE: Returns nil, if ObjKind != Typ.
+	SatisfiedInterfaces() []Implementation
+
+	// CheckTypKind returns a *KindError if the TypKind is not in set, or nil.
+	CheckTypKind(set TypKind) error
+
+	// AsStruct returns the struct view, if TypKind is Struct.
+	AsStruct() (StructView, bool)
+
+	// AsFunc returns the function view, if TypKind is Signature.
+	AsFunc() (FuncView, bool)
+
+	// AsInterface returns the interface view, if TypKind is Interface.
+	AsInterface() (InterfaceView, bool)
+
+	// AsMap returns the map view, if TypKind is Map.
+	AsMap() (MapView, bool)
+
+	// AsSlice returns the slice view, if TypKind is Slice.
+	AsSlice() (SliceView, bool)
+
+	// AsArray returns the array view, if TypKind is Array.
+	AsArray() (ArrayView, bool)
+
+	// AsChan returns the channel view, if TypKind is Chan.
+	AsChan() (ChanView, bool)
+
+	// AsPointer returns the pointer view, if TypKind is Pointer.
+	AsPointer() (PointerView, bool)
+
+	// AsBasic returns the basic view, if TypKind is Basic.
+	AsBasic() (BasicView, bool)
+
+	// AsTypeParam returns the type parameter view, if TypKind is TypeParam.
+	AsTypeParam() (TypeParamView, bool)
+
 	// Elem returns the element type.
 	// NOTE: Panic, if TypKind != (Array, Slice, Map, Chan and Pointer)
 	Elem() types.Type
@@ -142,6 +244,11 @@ type Facade interface {
 	// NOTE: Panic, if TypKind != Signature
 	Params() *types.Tuple
 
+	// ParamTypeExprs returns the type expressions of the parameters written in the source,
+	// which is in the same order as Params.
+	// NOTE: Panic, if TypKind != Signature
+	ParamTypeExprs() []string
+
 	// Recv returns the receiver of signature s (if a method), or nil if a
 	// function. It is ignored when comparing signatures for identity.
 	//
@@ -155,6 +262,11 @@ type Facade interface {
 	// NOTE: Panic, if TypKind != Signature
 	Results() *types.Tuple
 
+	// ResultTypeExprs returns the type expressions of the results written in the source,
+	// which is in the same order as Results.
+	// NOTE: Panic, if TypKind != Signature
+	ResultTypeExprs() []string
+
 	// Variadic reports whether the signature s is variadic.
 	// NOTE: Panic, if TypKind != Signature
 	Variadic() bool
@@ -184,10 +296,31 @@ type Facade interface {
 	// NOTE: Panic, if TypKind != Struct
 	FieldByName(name string) (field *StructField, found bool)
 
+	// FieldByIndex returns the nested field corresponding to the index sequence,
+	// such as the StructField.Index of the promoted field,
+	// and a boolean indicating if the field was found.
+	// NOTE: Panic, if TypKind != Struct
+	FieldByIndex(index []int) (field *StructField, found bool)
+
+	// FieldByNameFunc returns the struct field with a name that satisfies the match function
+	// and a boolean indicating if the field was found.
+	// The promoted fields are considered as the selectors of Go,
+	// and the multiple matched fields at the shallowest depth cancel each other out.
+	// NOTE: Panic, if TypKind != Struct
+	FieldByNameFunc(match func(string) bool) (field *StructField, found bool)
+
+	// AllFields returns the effective fields of the struct, including the promoted ones,
+	// following the rules of encoding/json, ordered by their index sequence.
+	// NOTE: Panic, if TypKind != Struct
+	AllFields() []*StructField
+
 	// ---------------------------------- TypKind = Interface ----------------------------------
 
 	// EmbeddedType returns the i'th embedded type of interface fa for 0 <= i < fa.NumEmbeddeds().
-	// NOTE: Panic, if TypKind != Interface
+	// NOTE:
+	//
+	//	Panic, if TypKind != Interface;
+	//	Returns nil, if the embedded type has no facade, such as a union or an interface literal.
 	IfaceEmbeddedType(i int) Facade
 
 	// IfaceEmpty returns true if fa is the empty interface.
@@ -207,6 +340,41 @@ type Facade interface {
 	// IfaceNumExplicitMethods returns the number of explicitly declared methods of interface fa.
 	// NOTE: Panic, if TypKind != Interface
 	IfaceNumExplicitMethods() int
+
+	// ---------------------------------- Generics ----------------------------------
+
+	// IsGeneric reports whether it is a generic type or function.
+	IsGeneric() bool
+
+	// NumTypeParams returns the number of type parameters of a generic type or function.
+	// NOTE: For a method of generic type, returns the number of receiver type parameters.
+	NumTypeParams() int
+
+	// TypeParams returns the type parameters of a generic type or function, or nil.
+	// NOTE: For a method of generic type, returns the receiver type parameters.
+	TypeParams() *types.TypeParamList
+
+	// TypeParam returns the i'th type parameter for 0 <= i < NumTypeParams().
+	// NOTE:
+	//  Panic, if i is not in the range [0, NumTypeParams());
+	//  The result's TypKind is TypeParam.
+	TypeParam(i int) Facade
+
+	// Instances returns the instantiations of the generic type or function in the program,
+	// sorted by the type string.
+	Instances() []types.Instance
+
+	// ---------------------------------- TypKind = TypeParam ----------------------------------
+
+	// Constraint returns the type constraint of type parameter.
+	// NOTE: Panic, if TypKind != TypeParam
+	Constraint() types.Type
+
+	// SetConstraint covers the type constraint expression of type parameter.
+	// If the type parameter shares its constraint with others, such as [K, V any],
+	// it is split into a separate declaration first.
+	// NOTE: Panic, if TypKind != TypeParam
+	SetConstraint(constraint string) error
 }
 
 type facade struct {
@@ -224,20 +392,37 @@ var _ Facade = (*facade)(nil)
 
 func (fa *facade) facadeIdentify() {}
 
-func (fa *facade) mustGetFacadeByObj(obj types.Object) *facade {
-	facade, idx := fa.pkg.getFacadeByObj(obj)
-	if idx < 0 {
-		panic(fmt.Sprintf("aster: mustGetFacadeByObj can't find %s", obj.String()))
+// prog returns the program of the facade.
+func (fa *facade) prog() *Program {
+	if fa.pkg == nil {
+		return nil
+	}
+	return fa.pkg.prog
+}
+
+// getFacadeByObj returns the facade of the object, in any package of the program.
+func (fa *facade) getFacadeByObj(obj types.Object) *facade {
+	if prog := fa.prog(); prog != nil {
+		return prog.facadeOf(obj)
 	}
-	return facade
+	return &facade{fset: fa.fset, obj: obj}
 }
 
-func (fa *facade) mustGetFacadeByTyp(typ types.Type) *facade {
-	facade, idx := fa.pkg.getFacadeByTyp(typ)
-	if idx < 0 {
-		panic(fmt.Sprintf("aster: mustGetFacadeByTyp can't find %s", typ.String()))
+// getFacadeByTyp returns the facade of the type, in any package of the program.
+// NOTE: Returns nil, if the type is neither named nor declared in the package of fa.
+func (fa *facade) getFacadeByTyp(typ types.Type) *facade {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Named:
+		return fa.getFacadeByObj(t.Origin().Obj())
+	case *types.TypeParam:
+		return fa.getFacadeByObj(t.Obj())
+	}
+	if fa.pkg != nil {
+		if facade, idx := fa.pkg.getFacadeByTyp(typ); idx != -1 {
+			return facade
+		}
 	}
-	return facade
+	return nil
 }
 
 // FileSet returns the *token.FileSet
@@ -307,6 +492,9 @@ func (fa *facade) Id() string { return fa.obj.Id() }
 // Name returns the type's name within its package for a defined type.
 // For other (non-defined) types it returns the empty string.
 func (fa *facade) Name() string {
+	if fa.ident == nil {
+		return fa.obj.Name()
+	}
 	return fa.ident.Name
 }
 
@@ -317,6 +505,10 @@ func (fa *facade) Doc() string {
 
 // SetDoc sets lead comment.
 func (fa *facade) SetDoc(text string) bool {
+	if fa.file == nil {
+		// no syntax
+		return false
+	}
 	if fa.doc == nil || len(fa.doc.List) == 0 {
 		doc := newCommentGroup()
 		common := doc.List[0]
@@ -426,7 +618,12 @@ func cleanDoc(text string) string {
 func (fa *facade) Exported() bool { return fa.obj.Exported() }
 
 // String previews the object formated code and comment.
-func (fa *facade) String() string { return fa.pkg.Preview(fa.ident) }
+func (fa *facade) String() string {
+	if fa.ident == nil {
+		return types.ObjectString(fa.obj, nil)
+	}
+	return fa.pkg.Preview(fa.ident)
+}
 
 // Underlying returns the underlying type of a type.
 func (fa *facade) Underlying() types.Type {
@@ -465,7 +662,7 @@ func (fa *facade) Method(i int) Facade {
 	if !ok {
 		return nil
 	}
-	return fa.mustGetFacadeByObj(t.Method(i))
+	return fa.getFacadeByObj(t.Method(i))
 }
 
 // AssertableTo reports whether it can be asserted to have T's type.
@@ -13,6 +13,7 @@ type File struct {
 	*ast.File
 	*PackageInfo
 	facades []*facade
+	decls   *declIndex // built on first use, reset when the facades change
 }
 
 // FindImportByPath find import alias by path, and return the first found result.
@@ -73,16 +74,19 @@ func (f *File) AddImport(importPath string, alias ...string) error {
 		}
 	}
 	f.Imports = append(f.Imports, newImport)
-	if f.Decls == nil || len(f.Decls) == 0 {
-		f.Decls = []ast.Decl{&ast.GenDecl{Tok: token.IMPORT}}
-	}
 	for i, decl := range f.Decls {
 		d, ok := decl.(*ast.GenDecl)
 		if ok && d.Tok == token.IMPORT {
 			f.Decls[i].(*ast.GenDecl).Specs = append(d.Specs, newImport)
-			break
+			return nil
 		}
 	}
+	// The import declarations must precede all other declarations.
+	f.Decls = append([]ast.Decl{&ast.GenDecl{
+		Tok:    token.IMPORT,
+		TokPos: f.Name.End(),
+		Specs:  []ast.Spec{newImport},
+	}}, f.Decls...)
 	return nil
 }
 
@@ -0,0 +1,135 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"go/build"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/andeya/aster/internal/buildutil"
+)
+
+// fsGOPATH is the virtual GOPATH where the file system of SetFS is mounted,
+// so that its root is the source root "$GOPATH/src".
+const fsGOPATH = "/aster.fs"
+
+// fsSrcRoot is the directory of the root of the file system of SetFS.
+const fsSrcRoot = fsGOPATH + "/src"
+
+// SetFS sets the file system to read the source code from, instead of the disk,
+// for both the created and imported packages, such as a testing/fstest.MapFS.
+//
+// The root of fsys is the source root of a GOPATH, i.e. the package "example.com/a"
+// is the directory "example.com/a" of fsys, which is the only GOPATH of the program.
+// The relative filenames of AddFile and directories of AddDirs are the paths in fsys,
+// and the files are reported under the virtual directory "/aster.fs/src".
+// The standard library is still loaded from GOROOT.
+// NOTE:
+//
+//	It should be called before AddFile and AddDirs to take effect on them;
+//	It does not work with ImportPatterns, which relies on 'go list' and the disk;
+//	The files can not be rewritten to fsys, use Format or FormatNode instead.
+func (prog *Program) SetFS(fsys fs.FS) (itself *Program) {
+	if prog.initiated {
+		return prog
+	}
+	prog.fsys = fsys
+	ctxt := prog.buildContext()
+	ctxt.GOPATH = fsGOPATH
+	prog.conf.Build = fsContext(ctxt, fsys)
+	if prog.conf.Cwd == "" {
+		prog.conf.Cwd = fsSrcRoot
+	}
+	return prog
+}
+
+// fsPath returns the filename in the virtual directory of the file system of SetFS,
+// if the name is relative.
+func (prog *Program) fsPath(name string) string {
+	if prog.fsys == nil || name == "" {
+		return name
+	}
+	slashed := filepath.ToSlash(name)
+	if _, ok := fsName(slashed); ok || filepath.IsAbs(name) {
+		return name
+	}
+	return path.Join(fsSrcRoot, slashed)
+}
+
+// fsName returns the name in the file system of SetFS of the virtual filename,
+// and reports whether the filename is in its virtual directory.
+func fsName(filename string) (string, bool) {
+	filename = filepath.ToSlash(filename)
+	if filename == fsSrcRoot {
+		return ".", true
+	}
+	if name := strings.TrimPrefix(filename, fsSrcRoot+"/"); name != filename {
+		return name, true
+	}
+	return "", false
+}
+
+// fsContext returns a build.Context reading the files in the virtual directory from fsys,
+// and the others through orig.
+func fsContext(orig *build.Context, fsys fs.FS) *build.Context {
+	ctxt := *orig // make a copy
+	ctxt.OpenFile = func(filename string) (io.ReadCloser, error) {
+		if name, ok := fsName(filename); ok {
+			return fsys.Open(name)
+		}
+		return buildutil.OpenFile(orig, filename)
+	}
+	ctxt.ReadDir = func(dir string) ([]os.FileInfo, error) {
+		name, ok := fsName(dir)
+		if !ok {
+			return buildutil.ReadDir(orig, dir)
+		}
+		entries, err := fs.ReadDir(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	}
+	ctxt.IsDir = func(dir string) bool {
+		if filepath.ToSlash(dir) == fsGOPATH {
+			return true
+		}
+		if name, ok := fsName(dir); ok {
+			info, err := fs.Stat(fsys, name)
+			return err == nil && info.IsDir()
+		}
+		return buildutil.IsDir(orig, dir)
+	}
+	ctxt.IsAbsPath = func(filename string) bool {
+		if _, ok := fsName(filename); ok {
+			return true
+		}
+		return buildutil.IsAbsPath(orig, filename)
+	}
+	return &ctxt
+}
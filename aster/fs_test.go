@@ -0,0 +1,63 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"example.com/a/a.go":      {Data: []byte("package a\n\nimport \"strings\"\n\nfunc A() string { return strings.ToUpper(\"a\") }\n")},
+		"example.com/b/b.go":      {Data: []byte("package b\n\nimport \"example.com/a\"\n\nvar B = a.A()\n")},
+		"example.com/b/c/c.go":    {Data: []byte("package c\n\nimport \"example.com/b\"\n\nvar C = b.B\n")},
+		"example.com/b/b_test.go": {Data: []byte("package b\n\nvar T = 1\n")},
+	}
+
+	prog, err := aster.NewProgram().SetFS(fsys).Import("example.com/b").Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, prog.Diagnostics())
+	b := prog.Package("example.com/b")
+	if assert.NotNil(t, b) {
+		assert.Equal(t, "string", b.Lookup(aster.Var, 0, "B")[0].Object().Type().String())
+		assert.Equal(t, "/aster.fs/src/example.com/b/b.go", b.Files[0].Filename)
+	}
+	assert.NotNil(t, prog.Package("example.com/a"))
+
+	prog, err = aster.NewProgram().SetFS(fsys).AddFile("example.com/b/b.go", nil).Load()
+	if assert.NoError(t, err) {
+		assert.Empty(t, prog.Diagnostics())
+		assert.Len(t, prog.Lookup(aster.Var, 0, "B"), 1)
+	}
+
+	prog, err = aster.NewProgram().SetFS(fsys).AddDirs("example.com/b").Load()
+	if assert.NoError(t, err) {
+		assert.Empty(t, prog.Diagnostics())
+		assert.Len(t, prog.InitialPackages(), 2)
+		assert.Len(t, prog.Lookup(aster.Var, 0, "C"), 1)
+		assert.Empty(t, prog.Lookup(aster.Var, 0, "T"))
+	}
+
+	_, err = aster.NewProgram().SetFS(fsys).AddFile("example.com/b/missing.go", nil).Load()
+	assert.Error(t, err)
+	_, err = aster.NewProgram().SetFS(fsys).ImportPatterns("example.com/b").Load()
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"sort"
+)
+
+// ---------------------------------- Generics ----------------------------------
+
+// typeParams returns the type parameters of a generic type or function, or nil.
+func (fa *facade) typeParams() *types.TypeParamList {
+	switch fa.ObjKind() {
+	case Typ:
+		if t, ok := fa.obj.Type().(*types.Named); ok && t.Obj() == fa.obj {
+			return t.TypeParams()
+		}
+	case Fun:
+		if t, ok := fa.obj.Type().(*types.Signature); ok {
+			if t.TypeParams() != nil {
+				return t.TypeParams()
+			}
+			return t.RecvTypeParams()
+		}
+	}
+	return nil
+}
+
+// IsGeneric reports whether it is a generic type or function.
+func (fa *facade) IsGeneric() bool {
+	return fa.NumTypeParams() > 0
+}
+
+// NumTypeParams returns the number of type parameters of a generic type or function.
+// NOTE: For a method of generic type, returns the number of receiver type parameters.
+func (fa *facade) NumTypeParams() int {
+	return fa.typeParams().Len()
+}
+
+// TypeParams returns the type parameters of a generic type or function, or nil.
+// NOTE: For a method of generic type, returns the receiver type parameters.
+func (fa *facade) TypeParams() *types.TypeParamList {
+	return fa.typeParams()
+}
+
+// TypeParam returns the i'th type parameter for 0 <= i < NumTypeParams().
+// NOTE:
+//
+//	Panic, if i is not in the range [0, NumTypeParams());
+//	The result's TypKind is TypeParam.
+func (fa *facade) TypeParam(i int) Facade {
+	tparams := fa.typeParams()
+	if i < 0 || i >= tparams.Len() {
+		panic("aster: TypeParam index out of bounds")
+	}
+	return fa.getFacadeByObj(tparams.At(i).Obj())
+}
+
+// NOTE: Panic, if TypKind != TypeParam
+func (fa *facade) typeParam() *types.TypeParam {
+	typ := fa.typ()
+	t, ok := typ.(*types.TypeParam)
+	if !ok {
+		panic(fmt.Sprintf("aster: typeParam of non-TypeParam TypKind: %T", typ))
+	}
+	return t
+}
+
+// Constraint returns the type constraint of type parameter.
+// NOTE: Panic, if TypKind != TypeParam
+func (fa *facade) Constraint() types.Type {
+	return fa.typeParam().Constraint()
+}
+
+// SetConstraint covers the type constraint expression of type parameter.
+// If the type parameter shares its constraint with others, such as [K, V any],
+// it is split into a separate declaration first.
+// NOTE: Panic, if TypKind != TypeParam
+func (fa *facade) SetConstraint(constraint string) error {
+	fa.typeParam()
+	list, field := fa.file.enclosingFieldList(fa.ident)
+	if field == nil {
+		return errors.New("aster: not found type parameter declaration")
+	}
+	expr, err := parser.ParseExpr(constraint)
+	if err != nil {
+		return err
+	}
+	if len(field.Names) > 1 {
+		field = fa.file.splitField(list, field, fa.ident)
+	}
+	clearPos(expr)
+	field.Type = expr
+	return nil
+}
+
+// enclosingFieldList returns the field declaring ident and the field list containing it.
+func (f *File) enclosingFieldList(ident *ast.Ident) (list *ast.FieldList, field *ast.Field) {
+	ast.Inspect(f.File, func(n ast.Node) bool {
+		if field != nil {
+			return false
+		}
+		l, ok := n.(*ast.FieldList)
+		if !ok {
+			return true
+		}
+		for _, fd := range l.List {
+			for _, name := range fd.Names {
+				if name == ident {
+					list, field = l, fd
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return
+}
+
+// splitField splits the field declaring multiple names into one field per name,
+// and returns the new field declaring ident.
+func (f *File) splitField(list *ast.FieldList, field *ast.Field, ident *ast.Ident) (newField *ast.Field) {
+	fields := make([]*ast.Field, 0, len(list.List)+len(field.Names)-1)
+	split := make(map[*ast.Ident]*ast.Field, len(field.Names))
+	for _, fd := range list.List {
+		if fd != field {
+			fields = append(fields, fd)
+			continue
+		}
+		for i, name := range field.Names {
+			nf := &ast.Field{
+				Names: []*ast.Ident{name},
+				Type:  field.Type,
+				Tag:   field.Tag,
+			}
+			if i == 0 {
+				nf.Doc = field.Doc
+			}
+			if i == len(field.Names)-1 {
+				nf.Comment = field.Comment
+			}
+			split[name] = nf
+			fields = append(fields, nf)
+		}
+	}
+	list.List = fields
+	for _, fa := range f.facades {
+		if nf, ok := split[fa.ident]; ok {
+			fa.node = nf
+		}
+	}
+	return split[ident]
+}
+
+// Instances returns the instantiations of the generic type or function in the program,
+// sorted by the type string.
+// NOTE: The receiver types of the generic methods are not instantiations.
+func (fa *facade) Instances() []types.Instance {
+	if !fa.IsGeneric() {
+		return nil
+	}
+	var list []types.Instance
+	for _, pkg := range fa.pkg.prog.allPackages {
+		var recvIdents map[*ast.Ident]bool
+	L:
+		for ident, inst := range pkg.info.Instances {
+			if pkg.info.Uses[ident] != fa.obj {
+				continue
+			}
+			if recvIdents == nil {
+				recvIdents = pkg.recvTypeIdents()
+			}
+			if recvIdents[ident] {
+				continue
+			}
+			for _, v := range list {
+				if types.Identical(v.Type, inst.Type) {
+					continue L
+				}
+			}
+			list = append(list, inst)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Type.String() < list[j].Type.String()
+	})
+	return list
+}
+
+// recvTypeIdents returns the identifiers in the receiver types of the methods.
+func (p *PackageInfo) recvTypeIdents() map[*ast.Ident]bool {
+	idents := make(map[*ast.Ident]bool)
+	for _, f := range p.Files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil {
+				continue
+			}
+			for _, field := range fn.Recv.List {
+				ast.Inspect(field.Type, func(n ast.Node) bool {
+					if ident, ok := n.(*ast.Ident); ok {
+						idents[ident] = true
+					}
+					return true
+				})
+			}
+		}
+	}
+	return idents
+}
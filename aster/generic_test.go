@@ -0,0 +1,88 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneric(t *testing.T) {
+	var src = `package test
+type Number interface{
+	~int | ~float64
+}
+type Pair[K comparable, V any] struct{
+	Key K
+	Value V
+}
+func (p *Pair[K, V]) Get() V { return p.Value }
+func Sum[T Number](a ...T) (s T) {
+	for _, v := range a {
+		s += v
+	}
+	return
+}
+var p1 = Pair[string, int]{Key: "a", Value: 1}
+var p2 Pair[int, bool]
+var p3 = Pair[string, int]{}
+var s1 = Sum(1, 2)
+`
+	prog, err := aster.LoadFile("../_out/generic.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair := prog.Lookup(aster.Typ, aster.Struct, "Pair")[0]
+	assert.True(t, pair.IsGeneric())
+	assert.Equal(t, 2, pair.NumTypeParams())
+	k := pair.TypeParam(0)
+	assert.Equal(t, "K", k.Name())
+	assert.Equal(t, aster.TypeParam, k.TypKind())
+	assert.Equal(t, "comparable", k.Constraint().String())
+	assert.Equal(t, "any", pair.TypeParam(1).Constraint().String())
+
+	instances := pair.Instances()
+	if assert.Len(t, instances, 2) {
+		assert.Equal(t, "test.Pair[int, bool]", instances[0].Type.String())
+		assert.Equal(t, "test.Pair[string, int]", instances[1].Type.String())
+	}
+
+	get := prog.Lookup(aster.Fun, aster.Signature, "Get")[0]
+	assert.Equal(t, 2, get.NumTypeParams())
+
+	sum := prog.Lookup(aster.Fun, aster.Signature, "Sum")[0]
+	assert.Equal(t, 1, sum.NumTypeParams())
+	assert.Len(t, sum.Instances(), 1)
+	assert.False(t, prog.Lookup(aster.Typ, aster.Interface, "Number")[0].IsGeneric())
+
+	p2 := prog.Lookup(aster.Var, aster.Struct, "p2")[0]
+	assert.False(t, p2.IsGeneric())
+	value, found := p2.FieldByName("Value")
+	assert.True(t, found)
+	assert.Equal(t, "Value", value.Name())
+
+	assert.NoError(t, k.SetConstraint("~string"))
+	assert.NoError(t, pair.TypeParam(1).SetConstraint("Number"))
+	// The constraint is placed by the printer, whatever its shape.
+	assert.NoError(t, sum.TypeParam(0).SetConstraint("interface {\n\t~int | ~float64\n\tString() string\n}"))
+	codes, err := pair.PackageInfo().Format()
+	assert.NoError(t, err)
+	for _, code := range codes {
+		assert.Contains(t, code, "type Pair[K ~string, V Number] struct")
+		assert.Contains(t, code, "func Sum[T interface {\n\t~int | ~float64\n\tString() string\n}](a ...T) (s T) {")
+	}
+}
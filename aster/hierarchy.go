@@ -0,0 +1,99 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import "go/types"
+
+// ScopeKind describes where an object is declared.
+type ScopeKind uint32
+
+// The list of possible scope kinds.
+const (
+	PackageScope ScopeKind = 1 << iota // declared at package level, including the methods
+	LocalScope                         // declared in a function
+	MemberScope                        // declared in a type, such as an interface method
+
+	AnyScopeKind = ^ScopeKind(0) // any scope kind
+)
+
+// In judges whether k is fully contained in set.
+func (k ScopeKind) In(set ScopeKind) bool {
+	return k&set == k
+}
+
+// ScopeKind returns where the object is declared.
+func (fa *facade) ScopeKind() ScopeKind {
+	switch {
+	case fa.IsPackageLevel():
+		return PackageScope
+	case fa.IsLocal():
+		return LocalScope
+	}
+	return MemberScope
+}
+
+// IsPackageLevel reports whether the object is declared at package level, including the methods.
+func (fa *facade) IsPackageLevel() bool {
+	if fa.obj.Pkg() == nil {
+		return false
+	}
+	if fn, ok := fa.obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			_, isIface := recv.Type().Underlying().(*types.Interface)
+			return !isIface
+		}
+	}
+	return isPackageLevel(fa.obj)
+}
+
+// IsLocal reports whether the object is local to some function.
+func (fa *facade) IsLocal() bool {
+	return isLocal(fa.obj)
+}
+
+// Scope returns the scope in which the object is declared,
+// or nil for a method, a struct field or an interface method.
+func (fa *facade) Scope() *types.Scope {
+	return fa.obj.Parent()
+}
+
+// Parent returns the innermost facade whose declaration encloses the declaration,
+// such as the function of a local type, or nil if none.
+func (fa *facade) Parent() Facade {
+	if parent := fa.parent(); parent != nil {
+		return parent
+	}
+	return nil
+}
+
+func (fa *facade) parent() *facade {
+	if fa.file == nil {
+		return nil
+	}
+	return fa.file.declIndex().parents[fa]
+}
+
+// Children returns the facades whose Parent is it, in the source order.
+func (fa *facade) Children() []Facade {
+	if fa.file == nil {
+		return nil
+	}
+	children := fa.file.declIndex().children[fa]
+	list := make([]Facade, len(children))
+	for i, child := range children {
+		list[i] = child
+	}
+	return list
+}
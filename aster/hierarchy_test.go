@@ -0,0 +1,79 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierarchy(t *testing.T) {
+	var src = `package test
+
+type I interface{ M() }
+
+type T struct{}
+
+func (T) M() {}
+
+func F(a int) {
+	type L struct{}
+	var b = func() {
+		var c int
+		_ = c
+	}
+	_, _ = a, b
+}
+`
+	prog, err := aster.LoadFile("hierarchy.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	names := func(list []aster.Facade) (names []string) {
+		for _, fa := range list {
+			names = append(names, fa.Name())
+		}
+		return
+	}
+	f := prog.Lookup(aster.Fun, 0, "F")[0]
+	assert.Nil(t, f.Parent())
+	assert.Equal(t, []string{"L", "b"}, names(f.Children()))
+	assert.True(t, f.IsPackageLevel())
+	assert.Equal(t, aster.PackageScope, f.ScopeKind())
+	assert.Equal(t, prog.InitialPackages()[0].Pkg.Scope(), f.Scope())
+
+	l := prog.Lookup(aster.Typ, 0, "L")[0]
+	assert.Equal(t, f, l.Parent())
+	assert.True(t, l.IsLocal())
+	assert.False(t, l.IsPackageLevel())
+	assert.Equal(t, aster.LocalScope, l.ScopeKind())
+
+	c := prog.Lookup(aster.Var, 0, "c")[0]
+	assert.Equal(t, "b", c.Parent().Name())
+	assert.Equal(t, f, c.Parent().Parent())
+
+	m := prog.Lookup(aster.Typ, 0, "T")[0].Method(0)
+	assert.True(t, m.IsPackageLevel())
+	assert.Nil(t, m.Scope())
+	im := prog.Lookup(aster.Typ, 0, "I")[0].IfaceExplicitMethod(0)
+	assert.Equal(t, aster.MemberScope, im.ScopeKind())
+	assert.Equal(t, "I", im.Parent().Name())
+
+	assert.Equal(t, []string{"L"}, names(prog.LookupScope(aster.LocalScope, aster.Typ, 0, "")))
+	assert.ElementsMatch(t, []string{"I", "T"}, names(prog.LookupScope(aster.PackageScope, aster.Typ, 0, "")))
+	assert.Len(t, prog.LookupScope(aster.AnyScopeKind, aster.Typ, 0, ""), 3)
+}
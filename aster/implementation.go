@@ -0,0 +1,119 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"go/types"
+	"sort"
+)
+
+// An Implementation is a named type satisfying a named interface.
+type Implementation struct {
+	// Type is the facade of the type.
+	Type Facade
+	// Interface is the facade of the interface.
+	Interface Facade
+	// Pointer reports whether only the pointer type satisfies the interface.
+	Pointer bool
+}
+
+// Implementations returns the named non-interface types in the program,
+// including the dependencies, which satisfy the interface by themselves or by their pointer types,
+// ordered by package path and name.
+//
+// The generic types are skipped, since only their instances can satisfy an interface.
+// NOTE: Panic, if iface TypKind != Interface
+func (prog *Program) Implementations(iface Facade) []Implementation {
+	I := iface.(*facade).iface()
+	var list []Implementation
+	for _, T := range prog.namedTypes() {
+		if _, isIface := T.Underlying().(*types.Interface); isIface || T.TypeParams().Len() > 0 {
+			continue
+		}
+		if pointer, ok := satisfies(T, I); ok {
+			list = append(list, Implementation{
+				Type:      prog.facadeOf(T.Obj()),
+				Interface: iface,
+				Pointer:   pointer,
+			})
+		}
+	}
+	sortImplementations(list, func(impl Implementation) types.Object { return impl.Type.Object() })
+	return list
+}
+
+// SatisfiedInterfaces returns the named interfaces in the program satisfied by the type
+// or its pointer type, except the empty ones, ordered by package path and name.
+//
+// The predeclared error interface is included, and comes first.
+// NOTE: Returns nil, if ObjKind != Typ.
+func (fa *facade) SatisfiedInterfaces() []Implementation {
+	prog := fa.prog()
+	if fa.ObjKind() != Typ || prog == nil {
+		return nil
+	}
+	T := fa.obj.Type()
+	var list []Implementation
+	errorType := types.Universe.Lookup("error").Type().(*types.Named)
+	for _, named := range append(prog.namedTypes(), errorType) {
+		I, ok := named.Underlying().(*types.Interface)
+		if !ok || I.Empty() || named.TypeParams().Len() > 0 || named.Obj() == fa.obj {
+			continue
+		}
+		if pointer, ok := satisfies(T, I); ok {
+			list = append(list, Implementation{
+				Type:      fa,
+				Interface: prog.facadeOf(named.Obj()),
+				Pointer:   pointer,
+			})
+		}
+	}
+	sortImplementations(list, func(impl Implementation) types.Object { return impl.Interface.Object() })
+	return list
+}
+
+// satisfies reports whether T or *T satisfies the interface,
+// and whether only *T does.
+func satisfies(T types.Type, iface *types.Interface) (pointer bool, ok bool) {
+	if types.Implements(T, iface) {
+		return false, true
+	}
+	if _, isIface := T.Underlying().(*types.Interface); isIface {
+		return false, false
+	}
+	if _, isPtr := T.(*types.Pointer); isPtr {
+		return false, false
+	}
+	return true, types.Implements(types.NewPointer(T), iface)
+}
+
+// sortImplementations sorts the list by package path and name of the object.
+func sortImplementations(list []Implementation, obj func(Implementation) types.Object) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := obj(list[i]), obj(list[j])
+		if pa, pb := pkgPath(a), pkgPath(b); pa != pb {
+			return pa < pb
+		}
+		return a.Name() < b.Name()
+	})
+}
+
+// pkgPath returns the package path of the object, or "" for the universe ones.
+func pkgPath(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path()
+}
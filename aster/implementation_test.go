@@ -0,0 +1,83 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImplementations(t *testing.T) {
+	var src = `package test
+
+import "io"
+
+type Plugin interface{ Name() string }
+
+type Named interface {
+	Plugin
+	Error() string
+}
+
+type A struct{}
+
+func (A) Name() string { return "a" }
+
+type B struct{}
+
+func (*B) Name() string { return "b" }
+
+func (*B) Error() string { return "b" }
+
+type C struct{}
+
+type R struct{}
+
+func (R) Read(p []byte) (int, error) { return 0, io.EOF }
+`
+	prog, err := aster.LoadFile("implementations.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	plugin := prog.Lookup(aster.Typ, 0, "Plugin")[0]
+	var impls []aster.Implementation
+	for _, impl := range prog.Implementations(plugin) {
+		// such as runtime.Func, which has the method Name() string
+		if impl.Type.PackageInfo().Pkg.Path() == "test" {
+			impls = append(impls, impl)
+		}
+	}
+	if assert.Len(t, impls, 2) {
+		assert.Equal(t, "A", impls[0].Type.Name())
+		assert.False(t, impls[0].Pointer)
+		assert.Equal(t, "B", impls[1].Type.Name())
+		assert.True(t, impls[1].Pointer)
+		assert.Equal(t, plugin, impls[1].Interface)
+	}
+
+	names := func(list []aster.Implementation) (names []string) {
+		for _, impl := range list {
+			names = append(names, impl.Interface.Name())
+		}
+		return
+	}
+	b := prog.Lookup(aster.Typ, 0, "B")[0]
+	assert.Equal(t, []string{"error", "Named", "Plugin"}, names(b.SatisfiedInterfaces()))
+	assert.Empty(t, prog.Lookup(aster.Typ, 0, "C")[0].SatisfiedInterfaces())
+	assert.Contains(t, names(prog.Lookup(aster.Typ, 0, "R")[0].SatisfiedInterfaces()), "Reader")
+	assert.Equal(t, []string{"error", "Plugin"}, names(prog.Lookup(aster.Typ, 0, "Named")[0].SatisfiedInterfaces()))
+}
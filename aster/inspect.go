@@ -18,12 +18,16 @@ import (
 	"fmt"
 	"go/ast"
 	"go/types"
+	"sort"
 
 	"github.com/andeya/aster/internal/loader"
 )
 
 func (p *PackageInfo) check() {
 	// log.Printf("Checking package %s...", p.String())
+	p.checked = true
+	p.checkErrors = nil
+	p.facades = nil
 L:
 	for ident, obj := range p.info.Defs {
 		var node ast.Node
@@ -58,11 +62,33 @@ L:
 		if file != nil {
 			p.addFacade(file, node, ident, obj)
 		} else {
-			fmt.Println("file==nil:", obj, node)
+			p.checkErrors = append(p.checkErrors, Diagnostic{
+				Package:  p,
+				Position: p.prog.fset.Position(ident.Pos()),
+				Severity: Soft,
+				Source:   LoaderSource,
+				Message:  fmt.Sprintf("not found the file declaring %s", obj),
+			})
+		}
+	}
+	// A package loaded without files, such as from export data,
+	// only has the facades of its package-level objects.
+	if len(p.Files) == 0 && p.Pkg != nil {
+		scope := p.Pkg.Scope()
+		for _, name := range scope.Names() {
+			p.facades = append(p.facades, p.newObjFacade(scope.Lookup(name)))
 		}
 	}
 }
 
+// ensureChecked builds the facades of the package on first use,
+// since those of the dependencies are not built by Load.
+func (p *PackageInfo) ensureChecked() {
+	if !p.checked {
+		p.check()
+	}
+}
+
 // Inspect traverses created and imported packages in the program.
 func (prog *Program) Inspect(fn func(Facade) bool) {
 	for _, pkg := range prog.InitialPackages() {
@@ -76,16 +102,46 @@ func (prog *Program) Inspect(fn func(Facade) bool) {
 	}
 }
 
+// InspectAll traverses all the packages in the program, including the dependencies,
+// in the order of package path.
+//
+// If filter is not nil, only the packages whose path it reports true for are traversed.
+// NOTE: The facades of the dependencies are built on first traversal.
+func (prog *Program) InspectAll(filter func(pkgPath string) bool, fn func(Facade) bool) {
+	pkgs := make([]*PackageInfo, 0, len(prog.allPackages))
+	for _, pkg := range prog.allPackages {
+		if filter == nil || filter(pkg.Pkg.Path()) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		return pkgs[i].Pkg.Path() < pkgs[j].Pkg.Path()
+	})
+	for _, pkg := range pkgs {
+		if !pkg.inspect(fn) {
+			return
+		}
+	}
+}
+
 // Lookup lookups facades in the program.
 //
 // Match any name if name="";
 // Match any ObjKind if objKindSet=0 or objKindSet=AnyObjKind;
 // Match any TypKind if typKindSet=0 or typKindSet=AnyTypKind;
 func (prog *Program) Lookup(objKindSet ObjKind, typKindSet TypKind, name string) (list []Facade) {
+	return prog.LookupScope(0, objKindSet, typKindSet, name)
+}
+
+// LookupScope is the same as Lookup, but also matches where the objects are declared.
+//
+// Match any ScopeKind if scopeKindSet=0 or scopeKindSet=AnyScopeKind;
+func (prog *Program) LookupScope(scopeKindSet ScopeKind, objKindSet ObjKind, typKindSet TypKind, name string) (list []Facade) {
 	prog.Inspect(func(fa Facade) bool {
 		if (name == "" || fa.Name() == name) &&
 			(typKindSet == 0 || fa.TypKind().In(typKindSet)) &&
-			(objKindSet == 0 || fa.ObjKind().In(objKindSet)) {
+			(objKindSet == 0 || fa.ObjKind().In(objKindSet)) &&
+			(scopeKindSet == 0 || fa.ScopeKind().In(scopeKindSet)) {
 			list = append(list, fa)
 		}
 		return true
@@ -106,13 +162,25 @@ func (prog *Program) FindFacade(typ types.Type) (fa Facade, found bool) {
 
 // Inspect traverses facades in the package.
 func (p *PackageInfo) Inspect(fn func(Facade) bool) {
+	p.inspect(fn)
+}
+
+// inspect traverses facades in the package, and reports whether fn never returned false.
+func (p *PackageInfo) inspect(fn func(Facade) bool) bool {
+	p.ensureChecked()
 	for _, file := range p.Files {
 		for _, fa := range file.facades {
 			if !fn(fa) {
-				return
+				return false
 			}
 		}
 	}
+	for _, fa := range p.facades {
+		if !fn(fa) {
+			return false
+		}
+	}
+	return true
 }
 
 // Lookup lookups facades in the package.
@@ -121,10 +189,18 @@ func (p *PackageInfo) Inspect(fn func(Facade) bool) {
 // Match any ObjKind if objKindSet=0 or objKindSet=AnyObjKind;
 // Match any TypKind if typKindSet=0 or typKindSet=AnyTypKind;
 func (p *PackageInfo) Lookup(objKindSet ObjKind, typKindSet TypKind, name string) (list []Facade) {
+	return p.LookupScope(0, objKindSet, typKindSet, name)
+}
+
+// LookupScope is the same as Lookup, but also matches where the objects are declared.
+//
+// Match any ScopeKind if scopeKindSet=0 or scopeKindSet=AnyScopeKind;
+func (p *PackageInfo) LookupScope(scopeKindSet ScopeKind, objKindSet ObjKind, typKindSet TypKind, name string) (list []Facade) {
 	p.Inspect(func(fa Facade) bool {
 		if (name == "" || fa.Name() == name) &&
 			(typKindSet == 0 || fa.TypKind().In(typKindSet)) &&
-			(objKindSet == 0 || fa.ObjKind().In(objKindSet)) {
+			(objKindSet == 0 || fa.ObjKind().In(objKindSet)) &&
+			(scopeKindSet == 0 || fa.ScopeKind().In(scopeKindSet)) {
 			list = append(list, fa)
 		}
 		return true
@@ -139,6 +215,7 @@ func (p *PackageInfo) FindFacade(typ types.Type) (fa Facade, found bool) {
 }
 
 func (p *PackageInfo) getFacade(ident *ast.Ident) (facade *facade, idx int) {
+	p.ensureChecked()
 	for _, file := range p.Files {
 		for _, facade = range file.facades {
 			if facade.ident == ident {
@@ -150,6 +227,7 @@ func (p *PackageInfo) getFacade(ident *ast.Ident) (facade *facade, idx int) {
 }
 
 func (p *PackageInfo) getFacadeByObj(obj types.Object) (facade *facade, idx int) {
+	p.ensureChecked()
 	for _, file := range p.Files {
 		for _, facade = range file.facades {
 			if facade.obj == obj {
@@ -157,10 +235,16 @@ func (p *PackageInfo) getFacadeByObj(obj types.Object) (facade *facade, idx int)
 			}
 		}
 	}
+	for _, facade = range p.facades {
+		if facade.obj == obj {
+			return
+		}
+	}
 	return nil, -1
 }
 
 func (p *PackageInfo) getFacadeByTyp(t types.Type) (facade *facade, idx int) {
+	p.ensureChecked()
 	for _, file := range p.Files {
 		for _, facade = range file.facades {
 			if facade.obj.Type() == t || facade.typ() == t {
@@ -168,13 +252,54 @@ func (p *PackageInfo) getFacadeByTyp(t types.Type) (facade *facade, idx int) {
 			}
 		}
 	}
+	for _, facade = range p.facades {
+		if facade.obj.Type() == t || facade.typ() == t {
+			return
+		}
+	}
 	return nil, -1
 }
 
+// facadeOf returns the facade of the object, which may be declared in a dependency
+// or have no syntax, such as the methods of a package loaded from export data.
+// The facades without syntax are created on first use.
+func (prog *Program) facadeOf(obj types.Object) *facade {
+	pkg := prog.allPackages[obj.Pkg()]
+	if pkg == nil {
+		// Such as the objects of the universe scope.
+		if fa, ok := prog.orphanFacades[obj]; ok {
+			return fa
+		}
+		fa := &facade{fset: prog.fset, obj: obj}
+		if prog.orphanFacades == nil {
+			prog.orphanFacades = make(map[types.Object]*facade)
+		}
+		prog.orphanFacades[obj] = fa
+		return fa
+	}
+	if fa, idx := pkg.getFacadeByObj(obj); idx != -1 {
+		return fa
+	}
+	fa := pkg.newObjFacade(obj)
+	pkg.facades = append(pkg.facades, fa)
+	return fa
+}
+
+// newObjFacade creates a facade without syntax.
+func (p *PackageInfo) newObjFacade(obj types.Object) *facade {
+	return &facade{
+		fset: p.prog.fset,
+		obj:  obj,
+		pkg:  p,
+	}
+}
+
 func (p *PackageInfo) addFacade(file *loader.File, node ast.Node, ident *ast.Ident, obj types.Object) {
 	for _, f := range p.Files {
 		if f.File == file.File {
+			f.decls = nil
 			f.facades = append(f.facades, &facade{
+				fset:  p.prog.fset,
 				file:  f,
 				node:  node,
 				obj:   obj,
@@ -206,6 +331,7 @@ func (p *PackageInfo) removeFacade(ident *ast.Ident) {
 		}
 		if find {
 			file.facades = newFacades
+			file.decls = nil
 			return
 		}
 	}
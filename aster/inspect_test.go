@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLookup(t *testing.T) {
@@ -59,3 +60,64 @@ func TestLookup(t *testing.T) {
 		t.Log(fa)
 	}
 }
+
+func TestInspectAll(t *testing.T) {
+	var src = `package test
+import "io"
+type R interface {
+	io.Reader
+	Close() error
+}
+type E interface {
+	error
+}
+type N interface {
+	~int | ~float64
+	interface{ String() string }
+}
+`
+	for _, fromExportData := range []bool{false, true} {
+		prog, err := aster.NewProgram().AddFile("inspectall.go", src).SetDepsFromExportData(fromExportData).Load()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		r := prog.Lookup(aster.Typ, aster.Interface, "R")[0]
+		reader := r.IfaceEmbeddedType(0)
+		if assert.NotNil(t, reader) {
+			assert.Equal(t, "Reader", reader.Name())
+			assert.Equal(t, "io", reader.PackageInfo().Pkg.Path())
+			assert.Equal(t, "Read", reader.IfaceExplicitMethod(0).Name())
+			assert.NotEmpty(t, reader.String())
+		}
+		e := prog.Lookup(aster.Typ, aster.Interface, "E")[0]
+		if assert.NotNil(t, e.IfaceEmbeddedType(0)) {
+			assert.Equal(t, "error", e.IfaceEmbeddedType(0).Name())
+			assert.Equal(t, "Error", e.IfaceEmbeddedType(0).IfaceExplicitMethod(0).Name())
+		}
+		num := prog.Lookup(aster.Typ, aster.Interface, "N")[0]
+		if assert.Equal(t, 2, num.IfaceNumEmbeddeds()) {
+			// the union and the interface literal have no facade,
+			// compared to nil as an interface, which assert.Nil would not tell
+			assert.True(t, num.IfaceEmbeddedType(0) == nil)
+			assert.True(t, num.IfaceEmbeddedType(1) == nil)
+		}
+
+		var io []string
+		prog.InspectAll(func(pkgPath string) bool { return pkgPath == "io" }, func(fa aster.Facade) bool {
+			assert.Equal(t, "io", fa.PackageInfo().Pkg.Path())
+			if fa.ObjKind() == aster.Typ && fa.Exported() {
+				io = append(io, fa.Name())
+			}
+			return true
+		})
+		assert.Contains(t, io, "Reader")
+		assert.Contains(t, io, "Writer")
+
+		var n int
+		prog.InspectAll(nil, func(fa aster.Facade) bool {
+			n++
+			return n < 3
+		})
+		assert.Equal(t, 3, n)
+	}
+}
@@ -32,10 +32,16 @@ func (fa *facade) iface() *types.Interface {
 }
 
 // EmbeddedType returns the i'th embedded type of interface fa for 0 <= i < fa.NumEmbeddeds().
-// NOTE: Panic, if TypKind != Interface
+// NOTE:
+//
+//	Panic, if TypKind != Interface;
+//	Returns nil, if the embedded type has no facade, such as a union or an interface literal.
 func (fa *facade) IfaceEmbeddedType(i int) Facade {
 	t := fa.iface().EmbeddedType(i)
-	return fa.mustGetFacadeByTyp(t)
+	if f := fa.getFacadeByTyp(t); f != nil {
+		return f
+	}
+	return nil
 }
 
 // IfaceEmpty returns true if fa is the empty interface.
@@ -54,7 +60,7 @@ func (fa *facade) IfaceEmpty() bool {
 //	The result's TypKind is Signature.
 func (fa *facade) IfaceExplicitMethod(i int) Facade {
 	fn := fa.iface().ExplicitMethod(i)
-	return fa.mustGetFacadeByObj(fn)
+	return fa.getFacadeByObj(fn)
 }
 
 // IfaceNumEmbeddeds returns the number of embedded types in interface fa.
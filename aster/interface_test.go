@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestInterface(t *testing.T) {
@@ -49,3 +50,64 @@ func(m *M)String()string{return "M"}
 		t.Fatalf("type M implements I2 interface")
 	}
 }
+
+func TestExplainImplements(t *testing.T) {
+	var src = `package test
+type I interface{
+	String() string
+	Print()
+}
+type Missing struct{}
+func (Missing) String() string { return "" }
+type Wrong struct{}
+func (Wrong) String() int { return 0 }
+func (Wrong) Print() {}
+type Ptr struct{}
+func (*Ptr) String() string { return "" }
+func (*Ptr) Print() {}
+type Field struct{ Print func() }
+func (Field) String() string { return "" }
+type N int
+`
+	prog, err := aster.LoadFile("explain.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	iface := prog.Lookup(aster.Typ, aster.Interface, "I")[0]
+	lookup := func(name string) aster.Facade { return prog.Lookup(aster.Typ, 0, name)[0] }
+
+	m := lookup("Missing").ExplainImplements(iface, false)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, aster.MissingMethod, m.Kind)
+		assert.Equal(t, "Print", m.Method.Name())
+		assert.Equal(t, "test.Missing does not implement test.I (missing method Print)", m.Error())
+	}
+	m = lookup("Wrong").ExplainImplements(iface, false)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, aster.WrongSignature, m.Kind)
+		assert.Equal(t, "String", m.Have.Name())
+		assert.Contains(t, m.Error(), "have String() int\n\t\twant String() string")
+	}
+	m = lookup("Ptr").ExplainImplements(iface, false)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, aster.PointerReceiver, m.Kind)
+	}
+	assert.Nil(t, lookup("Ptr").ExplainImplements(iface, true))
+	m = lookup("Field").ExplainImplements(iface, false)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, aster.FieldNotMethod, m.Kind)
+	}
+
+	n := lookup("N")
+	m = n.ExplainAssignableTo(iface)
+	if assert.NotNil(t, m) {
+		assert.Equal(t, aster.MissingMethod, m.Kind)
+		assert.False(t, n.AssignableTo(iface))
+	}
+	m = n.ExplainConvertibleTo(lookup("Missing"))
+	if assert.NotNil(t, m) {
+		assert.Equal(t, aster.TypeMismatch, m.Kind)
+		assert.Equal(t, "int is not compatible with struct{}", m.Error())
+	}
+	assert.Nil(t, n.ExplainConvertibleTo(n))
+}
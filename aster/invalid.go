@@ -1,70 +1,155 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package aster
 
 import (
 	"go/ast"
 	"go/types"
-	"reflect"
-	"strings"
-	"unsafe"
-
-	"github.com/andeya/goutil/tpack"
 )
 
-type invalidType struct {
-	types.Type
-	typeName string
+// IsInvalid reports whether the type is, or is composed of, a type that can not be resolved,
+// such as an undefined identifier or an unresolved import.
+// Use TypeExpr to get what is written in the source.
+func (fa *facade) IsInvalid() bool {
+	return containsInvalid(fa.typ())
 }
 
-func (t *invalidType) String() string {
-	return t.typeName
+// TypeExpr returns the type expression written in the source,
+// such as the type of variable, the type definition, the function signature
+// or the constraint of type parameter.
+// NOTE: Returns "", if the type is omitted (e.g. v := 1) or the syntax is unavailable.
+func (fa *facade) TypeExpr() string {
+	return fa.formatExpr(fa.typeExpr())
+}
+
+// ParamTypeExprs returns the type expressions of the parameters written in the source,
+// which is in the same order as Params.
+// NOTE: Panic, if TypKind != Signature
+func (fa *facade) ParamTypeExprs() []string {
+	fa.signature()
+	fnType, _ := fa.typeExpr().(*ast.FuncType)
+	if fnType == nil {
+		return nil
+	}
+	return fa.fieldTypeExprs(fnType.Params)
 }
 
-func (fa *facade) checkParams() {
-	fa.checkParamsOrResults("checkParams")
+// ResultTypeExprs returns the type expressions of the results written in the source,
+// which is in the same order as Results.
+// NOTE: Panic, if TypKind != Signature
+func (fa *facade) ResultTypeExprs() []string {
+	fa.signature()
+	fnType, _ := fa.typeExpr().(*ast.FuncType)
+	if fnType == nil {
+		return nil
+	}
+	return fa.fieldTypeExprs(fnType.Results)
 }
 
-func (fa *facade) checkResults() {
-	fa.checkParamsOrResults("checkResults")
+// IsInvalid reports whether the field type is, or is composed of, a type that can not be resolved.
+func (sf *StructField) IsInvalid() bool {
+	return containsInvalid(sf.obj.Type())
 }
 
-func (fa *facade) checkParamsOrResults(op string) {
-	defer func() { recover() }()
-	sig := fa.signature()
-	var fnType *ast.FuncType
-	switch t := fa.Node().(type) {
-	case *ast.FuncDecl:
-		fnType = t.Type
-	case *ast.FuncLit:
-		fnType = t.Type
-	default:
-		return
+// TypeExpr returns the field type expression written in the source.
+// NOTE: Returns "", if the syntax is unavailable.
+func (sf *StructField) TypeExpr() string {
+	if sf.node == nil {
+		return ""
 	}
-	var list []*ast.Field
-	var tup *types.Tuple
-	switch op {
-	case "checkParams":
-		list = fnType.Params.List
-		tup = sig.Params()
-	case "checkResults":
-		list = fnType.Results.List
-		tup = sig.Results()
+	return sf.facade.formatExpr(sf.node.Type)
+}
+
+// typeExpr returns the type expression of the declaration, or nil.
+func (fa *facade) typeExpr() ast.Expr {
+	if fa.ident == nil || fa.pkg == nil {
+		return nil
 	}
-	for i := tup.Len() - 1; i >= 0; i-- {
-		v := tup.At(i)
-		if !strings.HasSuffix(v.Type().String(), "invalid type") {
-			continue
+	_, nodes, _ := fa.pkg.pathEnclosingInterval(fa.ident.Pos(), fa.ident.End())
+	for _, node := range nodes {
+		switch decl := node.(type) {
+		case *ast.Ident:
+		case *ast.FuncDecl:
+			return decl.Type
+		case *ast.Field:
+			return decl.Type
+		case *ast.TypeSpec:
+			return decl.Type
+		case *ast.ValueSpec:
+			return decl.Type
+		default:
+			return nil
 		}
-		typeName, err := fa.FormatNode(list[i].Type)
-		if err != nil {
-			continue
+	}
+	return nil
+}
+
+// fieldTypeExprs returns the type expressions of the fields, one for each name.
+func (fa *facade) fieldTypeExprs(list *ast.FieldList) []string {
+	if list == nil {
+		return nil
+	}
+	var exprs []string
+	for _, field := range list.List {
+		expr := fa.formatExpr(field.Type)
+		exprs = append(exprs, expr)
+		for i := 1; i < len(field.Names); i++ {
+			exprs = append(exprs, expr)
+		}
+	}
+	return exprs
+}
+
+func (fa *facade) formatExpr(expr ast.Expr) string {
+	if expr == nil || fa.pkg == nil {
+		return ""
+	}
+	s, _ := fa.FormatNode(expr)
+	return s
+}
+
+// containsInvalid reports whether typ is, or is composed of, the invalid type.
+// The named types are not expanded, since they are checked by their own declarations.
+func containsInvalid(typ types.Type) bool {
+	switch t := typ.(type) {
+	case *types.Basic:
+		return t.Kind() == types.Invalid
+	case *types.Pointer:
+		return containsInvalid(t.Elem())
+	case *types.Slice:
+		return containsInvalid(t.Elem())
+	case *types.Array:
+		return containsInvalid(t.Elem())
+	case *types.Chan:
+		return containsInvalid(t.Elem())
+	case *types.Map:
+		return containsInvalid(t.Key()) || containsInvalid(t.Elem())
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			if containsInvalid(t.At(i).Type()) {
+				return true
+			}
 		}
-		objVal := reflect.ValueOf(v).Elem().FieldByName("object")
-		objTyp := objVal.FieldByName("typ")
-		fakeType := &invalidType{
-			Type:     v.Type(),
-			typeName: typeName,
+	case *types.Signature:
+		return containsInvalid(t.Params()) || containsInvalid(t.Results())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if containsInvalid(t.Field(i).Type()) {
+				return true
+			}
 		}
-		uptr := tpack.From(objTyp).Pointer()
-		*(*types.Type)(unsafe.Pointer(uptr)) = fakeType
 	}
+	return false
 }
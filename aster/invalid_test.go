@@ -0,0 +1,79 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalid(t *testing.T) {
+	var src = `package test
+
+import "example.com/missing"
+
+type S struct {
+	A int
+	B missing.Type
+}
+
+type U Undefined
+
+var v []missing.Type
+
+var ok = 1
+
+func F(a, b missing.Type, c int) (r *Undefined) { return nil }
+`
+	prog, _ := aster.LoadFile("invalid.go", src)
+	lookup := func(name string) aster.Facade {
+		list := prog.Lookup(aster.AnyObjKind, aster.AnyTypKind, name)
+		assert.Len(t, list, 1, name)
+		return list[0]
+	}
+
+	f := lookup("F")
+	assert.True(t, f.IsInvalid())
+	assert.Equal(t, "func(a, b missing.Type, c int) (r *Undefined)", f.TypeExpr())
+	assert.Equal(t, []string{"missing.Type", "missing.Type", "int"}, f.ParamTypeExprs())
+	assert.Equal(t, []string{"*Undefined"}, f.ResultTypeExprs())
+	// The go/types objects are never modified.
+	params := f.Params()
+	assert.Equal(t, types.Typ[types.Invalid], params.At(0).Type())
+	assert.Equal(t, "int", params.At(2).Type().String())
+
+	u := lookup("U")
+	assert.True(t, u.IsInvalid())
+	assert.Equal(t, "Undefined", u.TypeExpr())
+
+	v := lookup("v")
+	assert.True(t, v.IsInvalid())
+	assert.Equal(t, "[]missing.Type", v.TypeExpr())
+
+	okVar := lookup("ok")
+	assert.False(t, okVar.IsInvalid())
+	assert.Equal(t, "", okVar.TypeExpr())
+
+	s := lookup("S")
+	assert.True(t, s.IsInvalid())
+	a, _ := s.FieldByName("A")
+	assert.False(t, a.IsInvalid())
+	b, _ := s.FieldByName("B")
+	assert.True(t, b.IsInvalid())
+	assert.Equal(t, "missing.Type", b.TypeExpr())
+}
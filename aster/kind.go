@@ -53,6 +53,8 @@ const (
 	Interface
 	Map
 	Chan
+	TypeParam // type parameter of a generic type or function
+	Union     // union of terms in a constraint interface
 	named
 )
 
@@ -118,6 +120,10 @@ func GetTypKind(typ types.Type) TypKind {
 		return Map
 	case *types.Chan:
 		return Chan
+	case *types.TypeParam:
+		return TypeParam
+	case *types.Union:
+		return Union
 	case *types.Named:
 		return named
 	}
@@ -43,7 +43,7 @@ func (i ObjKind) String() string {
 	}
 }
 
-const _TypKind_name = "InvalidBasicArraySliceStructPointerTupleSignatureInterfaceMapChannamed"
+const _TypKind_name = "InvalidBasicArraySliceStructPointerTupleSignatureInterfaceMapChanTypeParamUnionnamed"
 
 var _TypKind_map = map[TypKind]string{
 	1:    _TypKind_name[0:7],
@@ -57,7 +57,9 @@ var _TypKind_map = map[TypKind]string{
 	256:  _TypKind_name[49:58],
 	512:  _TypKind_name[58:61],
 	1024: _TypKind_name[61:65],
-	2048: _TypKind_name[65:70],
+	2048: _TypKind_name[65:74],
+	4096: _TypKind_name[74:79],
+	8192: _TypKind_name[79:84],
 }
 
 func (i TypKind) String() string {
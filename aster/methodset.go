@@ -0,0 +1,91 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"go/types"
+)
+
+// A MethodSetEntry is a method in the method set of a type.
+type MethodSetEntry struct {
+	// Method is the facade of the method declaration, whose TypKind is Signature.
+	Method Facade
+	// Selection is the method selection of the type.
+	Selection *types.Selection
+	// Embedded is the path of the embedded fields through which the method is promoted,
+	// empty if the method is declared by the type itself.
+	Embedded []*types.Var
+	// PointerReceiver reports whether the method has a pointer receiver.
+	PointerReceiver bool
+	// Indirect reports whether a pointer indirection is required to get from the type to the method,
+	// see types.Selection.Indirect.
+	Indirect bool
+}
+
+// Promoted reports whether the method is promoted from an embedded field.
+func (m MethodSetEntry) Promoted() bool {
+	return len(m.Embedded) > 0
+}
+
+// MethodSet returns the method set of the type, or of its pointer type if ptr is true,
+// including the methods promoted from the embedded fields, ordered by their unique Id.
+//
+// The method set of the pointer type of an interface is empty, like in Go,
+// so ptr is ignored for an interface.
+// NOTE: Returns nil, if ObjKind != Typ.
+func (fa *facade) MethodSet(ptr bool) []MethodSetEntry {
+	if fa.ObjKind() != Typ {
+		return nil
+	}
+	T := fa.obj.Type()
+	if _, isIface := T.Underlying().(*types.Interface); ptr && !isIface {
+		T = types.NewPointer(T)
+	}
+	mset := types.NewMethodSet(T)
+	entries := make([]MethodSetEntry, 0, mset.Len())
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		fn := sel.Obj().(*types.Func)
+		entry := MethodSetEntry{
+			Method:    fa.getFacadeByObj(fn.Origin()),
+			Selection: sel,
+			Embedded:  embeddedPath(sel.Recv(), sel.Index()),
+			Indirect:  sel.Indirect(),
+		}
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			_, entry.PointerReceiver = recv.Type().(*types.Pointer)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// embeddedPath returns the embedded fields of the selection index, except the last one.
+func embeddedPath(T types.Type, index []int) []*types.Var {
+	var path []*types.Var
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := T.(*types.Pointer); ok {
+			T = ptr.Elem()
+		}
+		st, ok := T.Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		field := st.Field(i)
+		path = append(path, field)
+		T = field.Type()
+	}
+	return path
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodSet(t *testing.T) {
+	var src = `package test
+
+import "io"
+
+type Base struct{}
+
+func (Base) V() {}
+
+func (*Base) P() {}
+
+type S struct {
+	Base
+	io.Closer
+}
+
+func (S) Own() {}
+`
+	prog, err := aster.LoadFile("methodset.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s := prog.Lookup(aster.Typ, 0, "S")[0]
+	names := func(entries []aster.MethodSetEntry) (list []string) {
+		for _, m := range entries {
+			list = append(list, m.Method.Name())
+		}
+		return
+	}
+	assert.Equal(t, []string{"Close", "Own", "V"}, names(s.MethodSet(false)))
+	mset := s.MethodSet(true)
+	if assert.Equal(t, []string{"Close", "Own", "P", "V"}, names(mset)) {
+		closer := mset[0]
+		assert.True(t, closer.Promoted())
+		assert.Equal(t, "Closer", closer.Embedded[0].Name())
+		assert.Equal(t, "io", closer.Method.PackageInfo().Pkg.Path())
+		assert.False(t, mset[1].Promoted())
+		assert.False(t, mset[1].PointerReceiver)
+		p := mset[2]
+		assert.True(t, p.PointerReceiver)
+		assert.Equal(t, "Base", p.Embedded[0].Name())
+		assert.Equal(t, s.PackageInfo(), p.Method.PackageInfo())
+	}
+
+	base := prog.Lookup(aster.Typ, 0, "Base")[0]
+	assert.Equal(t, []string{"V"}, names(base.MethodSet(false)))
+	assert.Nil(t, prog.Lookup(aster.Fun, 0, "V")[0].MethodSet(false))
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// MismatchKind is the reason of a Mismatch.
+type MismatchKind int
+
+const (
+	// MissingMethod means the type has no method of the name.
+	MissingMethod MismatchKind = iota + 1
+	// WrongSignature means the method of the type has a different signature.
+	WrongSignature
+	// PointerReceiver means the method has a pointer receiver,
+	// so that only the pointer type has it.
+	PointerReceiver
+	// FieldNotMethod means the type has a field, instead of a method, of the name.
+	FieldNotMethod
+	// TypeMismatch means the types are incompatible, not because of the methods.
+	TypeMismatch
+)
+
+// String returns the name of the kind.
+func (k MismatchKind) String() string {
+	switch k {
+	case MissingMethod:
+		return "MissingMethod"
+	case WrongSignature:
+		return "WrongSignature"
+	case PointerReceiver:
+		return "PointerReceiver"
+	case FieldNotMethod:
+		return "FieldNotMethod"
+	case TypeMismatch:
+		return "TypeMismatch"
+	}
+	return fmt.Sprintf("MismatchKind(%d)", int(k))
+}
+
+// A Mismatch explains why a type does not implement an interface,
+// or is not assignable or convertible to another type.
+type Mismatch struct {
+	Kind MismatchKind
+	// Type is the type checked.
+	Type types.Type
+	// Target is the interface, or the type to assign or convert to.
+	Target types.Type
+	// Method is the method of the interface, nil if Kind is TypeMismatch.
+	Method *types.Func
+	// Have is the method or field of the name found in the type, if any.
+	Have types.Object
+}
+
+// Error returns the explanation, such as "T does not implement I (missing method M)".
+func (m *Mismatch) Error() string {
+	switch m.Kind {
+	case MissingMethod:
+		return fmt.Sprintf("%s does not implement %s (missing method %s)", m.Type, m.Target, m.Method.Name())
+	case WrongSignature:
+		return fmt.Sprintf("%s does not implement %s (wrong type for method %s)\n\t\thave %s%s\n\t\twant %s%s",
+			m.Type, m.Target, m.Method.Name(),
+			m.Have.Name(), signatureString(m.Have), m.Method.Name(), signatureString(m.Method))
+	case PointerReceiver:
+		return fmt.Sprintf("%s does not implement %s (method %s has pointer receiver)", m.Type, m.Target, m.Method.Name())
+	case FieldNotMethod:
+		return fmt.Sprintf("%s does not implement %s (%s is a field, not a method)", m.Type, m.Target, m.Method.Name())
+	}
+	return fmt.Sprintf("%s is not compatible with %s", m.Type, m.Target)
+}
+
+// ExplainImplements returns why it does not implement iface,
+// or nil if Implements returns true.
+// NOTE: Panic, if iface TypKind != Interface
+func (fa *facade) ExplainImplements(iface Facade, usePtr bool) *Mismatch {
+	t := fa.obj.Type()
+	if usePtr && fa.typKind() != Pointer {
+		t = types.NewPointer(t)
+	}
+	I := iface.(*facade)
+	return explainImplements(t, I.obj.Type(), I.iface())
+}
+
+// ExplainAssignableTo returns why it is not assignable to a variable of T's type,
+// or nil if AssignableTo returns true.
+func (fa *facade) ExplainAssignableTo(T Facade) *Mismatch {
+	V, target := fa.typ(), T.(*facade).typ()
+	if types.AssignableTo(V, target) {
+		return nil
+	}
+	if iface, ok := target.Underlying().(*types.Interface); ok {
+		if m := explainImplements(V, target, iface); m != nil {
+			return m
+		}
+	}
+	return &Mismatch{Kind: TypeMismatch, Type: V, Target: target}
+}
+
+// ExplainConvertibleTo returns why it is not convertible to a value of T's type,
+// or nil if ConvertibleTo returns true.
+func (fa *facade) ExplainConvertibleTo(T Facade) *Mismatch {
+	V, target := fa.typ(), T.(*facade).typ()
+	if types.ConvertibleTo(V, target) {
+		return nil
+	}
+	if iface, ok := target.Underlying().(*types.Interface); ok {
+		if m := explainImplements(V, target, iface); m != nil {
+			return m
+		}
+	}
+	return &Mismatch{Kind: TypeMismatch, Type: V, Target: target}
+}
+
+// explainImplements returns why V does not implement the interface target, or nil.
+func explainImplements(V, target types.Type, iface *types.Interface) *Mismatch {
+	if types.Implements(V, iface) {
+		return nil
+	}
+	method, _ := types.MissingMethod(V, iface, true)
+	if method == nil {
+		// Such as the type set of a constraint interface.
+		return &Mismatch{Kind: TypeMismatch, Type: V, Target: target}
+	}
+	m := &Mismatch{Kind: MissingMethod, Type: V, Target: target, Method: method}
+	have, _, _ := types.LookupFieldOrMethod(V, false, method.Pkg(), method.Name())
+	if have == nil {
+		if _, isPtr := V.(*types.Pointer); !isPtr {
+			if have, _, _ = types.LookupFieldOrMethod(V, true, method.Pkg(), method.Name()); have != nil {
+				if types.Identical(have.Type(), method.Type()) {
+					m.Kind = PointerReceiver
+					m.Have = have
+					return m
+				}
+			}
+		}
+	}
+	switch have.(type) {
+	case *types.Func:
+		m.Kind = WrongSignature
+		m.Have = have
+	case *types.Var:
+		m.Kind = FieldNotMethod
+		m.Have = have
+	}
+	return m
+}
+
+// signatureString returns the signature of the function without "func".
+func signatureString(fn types.Object) string {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok {
+		return ""
+	}
+	return types.TypeString(sig, nil)[len("func"):]
+}
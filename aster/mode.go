@@ -0,0 +1,116 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/types"
+
+	"github.com/andeya/aster/internal/loader"
+)
+
+// A LoadMode controls the amount of detail to load, trading memory against detail.
+//
+// The declarations and their types are always loaded.
+type LoadMode int
+
+const (
+	// NeedComments parses the comments of the files.
+	// NOTE: Without it, the comments are lost when the files are rewritten.
+	NeedComments LoadMode = 1 << iota
+
+	// NeedFuncBodies type-checks the function bodies of the initial packages.
+	NeedFuncBodies
+
+	// NeedTypesInfo keeps the types and values of all the expressions.
+	// Without it, only the types of the type expressions are kept.
+	// NOTE: The definitions, the uses, the implicit objects, the selections and the instances
+	// are always kept, which References, Instances and Rename depend on.
+	NeedTypesInfo
+
+	// NeedDepsSyntax keeps the syntax trees and the types.Info of the dependencies.
+	// Without it, only their types are kept.
+	NeedDepsSyntax
+
+	// NeedDepsFuncBodies type-checks the function bodies of the dependencies.
+	NeedDepsFuncBodies
+)
+
+const (
+	// LoadNames loads the declarations of the initial packages, without comments or function bodies.
+	LoadNames LoadMode = 0
+
+	// LoadDecls loads the declarations of the initial packages with comments and types info,
+	// without type-checking the function bodies.
+	LoadDecls = NeedComments | NeedTypesInfo
+
+	// LoadSyntax loads the initial packages in full, and the syntax trees of the dependencies,
+	// without type-checking their function bodies. It is the default mode.
+	LoadSyntax = LoadDecls | NeedFuncBodies | NeedDepsSyntax
+
+	// LoadAllSyntax is the same as LoadSyntax, but also type-checks the function bodies of the dependencies.
+	LoadAllSyntax = LoadSyntax | NeedDepsFuncBodies
+)
+
+// SetLoadMode sets the amount of detail to load, the default is LoadSyntax.
+// NOTE: It should be called before AddFile and AddDirs to take effect on the parsed files.
+func (prog *Program) SetLoadMode(mode LoadMode) (itself *Program) {
+	if !prog.initiated {
+		prog.mode = mode
+		if mode&NeedComments != 0 {
+			prog.conf.ParserMode |= parser.ParseComments
+		} else {
+			prog.conf.ParserMode &^= parser.ParseComments
+		}
+	}
+	return prog
+}
+
+// LoadMode returns the amount of detail to load.
+func (prog *Program) LoadMode() LoadMode {
+	return prog.mode
+}
+
+// typeCheckFuncBodies reports whether to type-check the function bodies of the package.
+func (prog *Program) typeCheckFuncBodies(initial bool) bool {
+	if initial {
+		return prog.mode&NeedFuncBodies != 0
+	}
+	return prog.mode&NeedDepsFuncBodies != 0
+}
+
+// trim releases the details of the loaded package which are not needed by the load mode.
+func (prog *Program) trim(info *loader.PackageInfo, initial bool) {
+	if !initial && prog.mode&NeedDepsSyntax == 0 {
+		info.Files = nil
+		info.Info = types.Info{}
+		return
+	}
+	if prog.mode&NeedTypesInfo == 0 {
+		trimTypesInfo(&info.Info)
+	}
+}
+
+// trimTypesInfo keeps only the types of the type expressions in info.Types.
+func trimTypesInfo(info *types.Info) {
+	kept := make(map[ast.Expr]types.TypeAndValue)
+	for expr, tv := range info.Types {
+		if tv.IsType() {
+			kept[expr] = tv
+		}
+	}
+	info.Types = kept
+}
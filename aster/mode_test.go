@@ -0,0 +1,105 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMode(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"q/q.go": "package q\n\nfunc Q() int { return \"q\" }\n",
+		"p/p.go": "package p\n\nimport \"example.com/m/q\"\n\n// F does nothing.\nfunc F() { var x int = q.Q(); _ = x + \"p\" }\n",
+	})
+	for _, patterns := range []bool{false, true} {
+		load := func(mode aster.LoadMode) (*aster.Program, aster.Facade) {
+			prog := aster.NewProgram().SetWorkDir(dir).SetLoadMode(mode)
+			if patterns {
+				prog.ImportPatterns("./p")
+			} else {
+				prog.Import("./p")
+			}
+			_, err := prog.Load()
+			if !assert.NoError(t, err) {
+				return nil, nil
+			}
+			assert.Equal(t, mode, prog.LoadMode())
+			return prog, prog.InitialPackages()[0].Lookup(aster.Fun, 0, "F")[0]
+		}
+		depsFiles := func(prog *aster.Program) int {
+			if pkg := prog.Package("example.com/m/q"); pkg != nil {
+				return len(pkg.Files)
+			}
+			return -1
+		}
+
+		prog, fa := load(aster.NewProgram().LoadMode())
+		if prog == nil {
+			continue
+		}
+		assert.Equal(t, aster.LoadSyntax, prog.LoadMode())
+		assert.Equal(t, "F does nothing.\n", fa.Doc())
+		assert.NotEmpty(t, prog.InitialPackages()[0].Diagnostics())
+		assert.Equal(t, 1, depsFiles(prog))
+
+		prog, fa = load(aster.LoadDecls)
+		if prog == nil {
+			continue
+		}
+		assert.Equal(t, "F does nothing.\n", fa.Doc())
+		assert.Empty(t, prog.InitialPackages()[0].Diagnostics())
+
+		prog, fa = load(aster.LoadNames)
+		if prog == nil {
+			continue
+		}
+		assert.Equal(t, "", fa.Doc())
+		assert.Empty(t, prog.InitialPackages()[0].Diagnostics())
+		assert.Equal(t, 0, depsFiles(prog))
+
+		prog, _ = load(aster.LoadAllSyntax)
+		if prog == nil {
+			continue
+		}
+		var depsErrors int
+		for _, d := range prog.Diagnostics() {
+			if d.Package.Pkg.Name() == "q" {
+				depsErrors++
+			}
+		}
+		assert.Equal(t, 1, depsErrors)
+	}
+}
+
+func TestLoadModeReferences(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"p/p.go": "package p\n\ntype L[T any] []T\n\nfunc G() {}\n\nvar V, I = G, L[int]{}\n\nfunc F() { G() }\n",
+	})
+	for mode, refs := range map[aster.LoadMode]int{aster.LoadNames: 1, aster.LoadDecls: 1, aster.LoadSyntax: 2} {
+		prog, err := aster.NewProgram().SetWorkDir(dir).SetLoadMode(mode).Import("./p").Load()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		pkg := prog.InitialPackages()[0]
+		g := pkg.Lookup(aster.Fun, 0, "G")[0]
+		assert.Len(t, g.References(), refs, "mode %d", mode)
+		assert.Len(t, pkg.Lookup(aster.Typ, 0, "L")[0].Instances(), 1, "mode %d", mode)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/andeya/aster/internal/loader"
+	"github.com/andeya/aster/internal/packages"
+)
+
+// LoadPatterns loads the packages matched by the patterns in module mode and loads a new program.
+//
+// The patterns are interpreted by 'go list', so module-aware paths
+// such as "./..." work from a module root (go.mod, replace directives and go.work are honored).
+func LoadPatterns(patterns ...string) (*Program, error) {
+	return NewProgram().ImportPatterns(patterns...).Load()
+}
+
+// LoadPatternsWithTests is the same as LoadPatterns,
+// but also loads the *_test.go files of the matched packages.
+//
+// The package will be augmented by its in-package test files,
+// and the external test package, if any, will be added to the created packages.
+func LoadPatternsWithTests(patterns ...string) (*Program, error) {
+	return NewProgram().ImportPatternsWithTests(patterns...).Load()
+}
+
+// ImportPatterns adds the package patterns that will be loaded in module mode.
+//
+// The patterns are interpreted by 'go list' in the program's working directory.
+// NOTE: can not be mixed with AddFile, Import and ImportWithTests.
+func (prog *Program) ImportPatterns(patterns ...string) (itself *Program) {
+	if !prog.initiated && prog.initialError == nil {
+		prog.patterns = append(prog.patterns, patterns...)
+	}
+	return prog
+}
+
+// ImportPatternsWithTests is the same as ImportPatterns,
+// but also loads the *_test.go files of the matched packages.
+func (prog *Program) ImportPatternsWithTests(patterns ...string) (itself *Program) {
+	if !prog.initiated && prog.initialError == nil {
+		prog.patterns = append(prog.patterns, patterns...)
+		prog.patternTests = true
+	}
+	return prog
+}
+
+// SetWorkDir sets the directory in which relative package paths and patterns are resolved.
+// If empty, the current working directory will be used.
+func (prog *Program) SetWorkDir(dir string) (itself *Program) {
+	if !prog.initiated {
+		prog.conf.Cwd = dir
+		// In module mode, go/build locates the main module from the directory.
+		prog.buildContext().Dir = dir
+	}
+	return prog
+}
+
+// loadPatterns loads the patterns through 'go list' and converts
+// the result to loader.Program, so that the rest of the pipeline
+// is shared with the GOPATH loader.
+func (prog *Program) loadPatterns(ctx context.Context) (*loader.Program, error) {
+	if len(prog.conf.CreatePkgs) > 0 || len(prog.conf.ImportPkgs) > 0 {
+		return nil, errors.New("can not mix patterns with files or import paths")
+	}
+	if prog.fsys != nil {
+		return nil, errors.New("can not load patterns from a file system set by SetFS")
+	}
+	if prog.conf.Fset == nil {
+		prog.conf.Fset = token.NewFileSet()
+	}
+	cfg := &packages.Config{
+		Mode:       packages.LoadAllSyntax,
+		Context:    ctx,
+		Dir:        prog.conf.Cwd,
+		Fset:       prog.conf.Fset,
+		Tests:      prog.patternTests,
+		Overlay:    prog.overlay,
+		Env:        prog.env(),
+		BuildFlags: prog.buildFlags(),
+
+		Parallelism: prog.parallelism,
+	}
+	cfg.BeforeTypeCheck, cfg.AfterTypeCheck = prog.packagesProgressHooks()
+	cfg.TypeCheckFuncBodies = func(_ *packages.Package, initial bool) bool {
+		return prog.typeCheckFuncBodies(initial)
+	}
+	if prog.mode&NeedComments == 0 {
+		cfg.ParseFile = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			return parser.ParseFile(fset, filename, src, parser.AllErrors)
+		}
+	}
+	if prog.depsFromExportData {
+		// Without NeedDeps, the dependencies are loaded from export data.
+		cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedImports | packages.NeedExportsFile | packages.NeedTypes |
+			packages.NeedTypesSizes | packages.NeedSyntax | packages.NeedTypesInfo
+		cfg.ListDeps = true
+	}
+	pkgs, err := packages.Load(cfg, prog.patterns...)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &loader.Program{
+		Fset:        prog.conf.Fset,
+		Imported:    make(map[string]*loader.PackageInfo, len(pkgs)),
+		AllPackages: make(map[*types.Package]*loader.PackageInfo, 128),
+	}
+	var solved = make(map[*packages.Package]*loader.PackageInfo, 128)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		info := prog.convertPackage(pkg)
+		solved[pkg] = info
+		p.AllPackages[pkg.Types] = info
+	})
+
+	for _, pkg := range pkgs {
+		info := solved[pkg]
+		switch {
+		case info == nil || isTestMain(pkg):
+			// the synthesized test executable is not interesting
+		case strings.HasSuffix(pkg.PkgPath, "_test"):
+			p.Created = append(p.Created, info)
+		default:
+			// Prefer the test variant of a package, which is augmented by
+			// its in-package test files.
+			if _, ok := p.Imported[pkg.PkgPath]; ok && pkg.ID == pkg.PkgPath {
+				break
+			}
+			p.Imported[pkg.PkgPath] = info
+		}
+	}
+	if len(p.Imported)+len(p.Created) == 0 {
+		return nil, errors.New("no initial packages were loaded")
+	}
+	return p, nil
+}
+
+// convertPackage converts the packages.Package to loader.PackageInfo.
+func (prog *Program) convertPackage(pkg *packages.Package) *loader.PackageInfo {
+	info := &loader.PackageInfo{
+		Pkg:                   pkg.Types,
+		Importable:            !strings.HasSuffix(pkg.PkgPath, "_test"),
+		TransitivelyErrorFree: !pkg.IllTyped,
+	}
+	if pkg.TypesInfo != nil {
+		info.Info = *pkg.TypesInfo
+	}
+	for i, f := range pkg.Syntax {
+		filename := prog.conf.Fset.Position(f.Pos()).Filename
+		if filename == "" && i < len(pkg.CompiledGoFiles) {
+			filename = pkg.CompiledGoFiles[i]
+		}
+		info.Files = append(info.Files, &loader.File{Filename: filename, File: f})
+	}
+	// Keep the type errors as types.Error, which tells the soft ones.
+	var errs []error
+	for _, e := range pkg.Errors {
+		if e.Kind != packages.TypeError {
+			errs = append(errs, e)
+		}
+	}
+	for _, e := range pkg.TypeErrors {
+		errs = append(errs, e)
+	}
+	for _, e := range errs {
+		info.Errors = append(info.Errors, e)
+		if prog.conf.TypeChecker.Error != nil {
+			prog.conf.TypeChecker.Error(e)
+		}
+	}
+	return info
+}
+
+// isTestMain reports whether the package is the test executable synthesized by 'go list -test'.
+func isTestMain(pkg *packages.Package) bool {
+	return pkg.Name == "main" && strings.HasSuffix(pkg.PkgPath, ".test")
+}
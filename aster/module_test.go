@@ -0,0 +1,48 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPatterns(t *testing.T) {
+	prog, err := aster.LoadPatterns("../tools/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := prog.Package("github.com/andeya/aster/tools")
+	if !assert.NotNil(t, pkg) {
+		return
+	}
+	assert.Len(t, pkg.Lookup(aster.Fun, aster.Signature, "Format"), 1)
+	assert.NotNil(t, prog.Package("go/format"))
+
+	prog, err = aster.LoadPatternsWithTests("../tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg = prog.Package("github.com/andeya/aster/tools")
+	if !assert.NotNil(t, pkg) {
+		return
+	}
+	assert.Len(t, pkg.Lookup(aster.Fun, aster.Signature, "TestReplaceFile"), 1)
+
+	_, err = aster.NewProgram().AddFile("../_out/module.go", "package test").ImportPatterns("../tools").Load()
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOverlay(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nfunc A() int { return 1 }\n",
+		"b/b.go": "package b\n\nconst B = 1\n",
+	})
+	overlay := map[string][]byte{
+		filepath.Join(dir, "a/a.go"): []byte("package a\n\nfunc Overlaid() {}\n"),
+		filepath.Join(dir, "b/b.go"): []byte("package b\n\nconst Overlaid = 1\n"),
+	}
+
+	// imported package
+	prog, err := aster.NewProgram().SetWorkDir(dir).SetOverlay(overlay).Import("./a").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, prog.Lookup(aster.Fun, 0, "Overlaid"), 1)
+	assert.Empty(t, prog.Lookup(aster.Fun, 0, "A"))
+
+	// created package
+	prog, err = aster.NewProgram().SetOverlay(overlay).AddFile(filepath.Join(dir, "b/b.go"), nil).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, prog.Lookup(aster.Con, 0, "Overlaid"), 1)
+
+	// module mode
+	prog, err = aster.NewProgram().SetWorkDir(dir).SetOverlay(overlay).ImportPatterns("./...").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, prog.Lookup(aster.Fun, 0, "Overlaid"), 1)
+	assert.Len(t, prog.Lookup(aster.Con, 0, "Overlaid"), 1)
+}
@@ -34,6 +34,9 @@ type PackageInfo struct {
 	transitivelyErrorFree bool           // true if Pkg and all its dependencies are free of errors
 	loaderFiles           []*loader.File // syntax trees for the package's loaderFiles
 	Errors                []error        // non-nil if the package had errors
+	checkErrors           []Diagnostic   // the objects whose facades could not be built
+	checked               bool           // true if the facades have been built
+	facades               []*facade      // the facades without syntax, such as those of a package without files
 	info                  types.Info     // type-checker deductions.
 	Files                 []*File
 }
@@ -0,0 +1,234 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// Position returns the position of the declared name.
+func (fa *facade) Position() token.Position {
+	return position(fa.fset, fa.obj.Pos())
+}
+
+// Range returns the start and end positions of the declaration, including its doc comment.
+//
+// The declaration of a type, constant or variable is the whole GenDecl if it is not grouped,
+// otherwise the spec in the group.
+// NOTE: Returns the position of the declared name for both, if the facade has no syntax.
+func (fa *facade) Range() (start, end token.Position) {
+	from, to := fa.declRange()
+	return position(fa.fset, from), position(fa.fset, to)
+}
+
+// Source returns the original source code of the declaration, including its doc comment,
+// as it was loaded.
+// NOTE: The mutations of the syntax tree are not reflected.
+func (fa *facade) Source() ([]byte, error) {
+	from, to := fa.declRange()
+	return sourceOf(fa.prog(), fa.fset, from, to)
+}
+
+// declRange returns the range of the declaration, including its doc comment.
+func (fa *facade) declRange() (start, end token.Pos) {
+	if fa.ident == nil || fa.pkg == nil {
+		return fa.obj.Pos(), fa.obj.Pos()
+	}
+	decl, doc := fa.decl()
+	if decl == nil {
+		return fa.ident.Pos(), fa.ident.End()
+	}
+	start, end = decl.Pos(), decl.End()
+	if doc != nil && doc.Pos() < start {
+		start = doc.Pos()
+	}
+	return start, end
+}
+
+// declIndex indexes the declaration ranges of the facades of a file,
+// which are computed only once, since each one walks the syntax tree.
+type declIndex struct {
+	sorted   []*facade // ordered by start, the enclosing one first
+	ranges   map[*facade]posRange
+	parents  map[*facade]*facade
+	children map[*facade][]*facade // in the source order
+}
+
+type posRange struct {
+	start, end token.Pos
+}
+
+// declIndex returns the index of the declaration ranges of the facades of the file,
+// which is built on first use.
+func (f *File) declIndex() *declIndex {
+	f.PackageInfo.ensureChecked()
+	if f.decls == nil {
+		f.decls = newDeclIndex(f.facades)
+	}
+	return f.decls
+}
+
+func newDeclIndex(facades []*facade) *declIndex {
+	idx := &declIndex{
+		sorted:   append([]*facade(nil), facades...),
+		ranges:   make(map[*facade]posRange, len(facades)),
+		parents:  make(map[*facade]*facade, len(facades)),
+		children: make(map[*facade][]*facade),
+	}
+	for _, fa := range facades {
+		start, end := fa.declRange()
+		idx.ranges[fa] = posRange{start, end}
+	}
+	sort.Slice(idx.sorted, func(i, j int) bool {
+		a, b := idx.ranges[idx.sorted[i]], idx.ranges[idx.sorted[j]]
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		if a.end != b.end {
+			return a.end > b.end
+		}
+		return idx.sorted[i].ident.Pos() < idx.sorted[j].ident.Pos()
+	})
+	// The declarations are either nested or disjoint,
+	// so the stack of the enclosing ones gives the parent of each in one pass,
+	// and the children of each in the source order.
+	var stack []*facade
+	for _, fa := range idx.sorted {
+		r := idx.ranges[fa]
+		for len(stack) > 0 && idx.ranges[stack[len(stack)-1]].end < r.end {
+			stack = stack[:len(stack)-1]
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			// Such as the names declared by the same spec.
+			if parent := stack[i]; idx.ranges[parent] != r {
+				idx.parents[fa] = parent
+				idx.children[parent] = append(idx.children[parent], fa)
+				break
+			}
+		}
+		stack = append(stack, fa)
+	}
+	return idx
+}
+
+// innermost returns the innermost facade whose declaration contains the position, or nil if none.
+func (idx *declIndex) innermost(pos token.Pos) *facade {
+	i := sort.Search(len(idx.sorted), func(i int) bool {
+		return idx.ranges[idx.sorted[i]].start > pos
+	})
+	if i == 0 {
+		return nil
+	}
+	for fa := idx.sorted[i-1]; fa != nil; fa = idx.parents[fa] {
+		if r := idx.ranges[fa]; r.start <= pos && pos < r.end {
+			return fa
+		}
+	}
+	return nil
+}
+
+// decl returns the declaration node of the facade, and its doc comment.
+func (fa *facade) decl() (ast.Node, *ast.CommentGroup) {
+	_, nodes, _ := fa.pkg.pathEnclosingInterval(fa.ident.Pos(), fa.ident.End())
+	for i, node := range nodes {
+		switch decl := node.(type) {
+		case *ast.FuncDecl:
+			return decl, decl.Doc
+		case *ast.Field:
+			return decl, decl.Doc
+		case *ast.TypeSpec:
+			return specDecl(decl, decl.Doc, nodes[i+1:])
+		case *ast.ValueSpec:
+			return specDecl(decl, decl.Doc, nodes[i+1:])
+		case *ast.Ident:
+		default:
+			return node, nil
+		}
+	}
+	return nil, nil
+}
+
+// specDecl returns the enclosing GenDecl of the spec if it is not grouped, otherwise the spec.
+func specDecl(spec ast.Spec, doc *ast.CommentGroup, ancestors []ast.Node) (ast.Node, *ast.CommentGroup) {
+	if len(ancestors) > 0 {
+		if gen, ok := ancestors[0].(*ast.GenDecl); ok && !gen.Lparen.IsValid() {
+			return gen, gen.Doc
+		}
+	}
+	return spec, doc
+}
+
+// Position returns the position of the field's name, or of its type if embedded.
+func (sf *StructField) Position() token.Position {
+	return position(sf.facade.fset, sf.obj.Pos())
+}
+
+// Range returns the start and end positions of the field, including its doc comment.
+// NOTE: Returns the position of the field's name for both, if the field has no syntax.
+func (sf *StructField) Range() (start, end token.Position) {
+	from, to := sf.fieldRange()
+	return position(sf.facade.fset, from), position(sf.facade.fset, to)
+}
+
+// Source returns the original source code of the field, including its doc comment,
+// as it was loaded.
+// NOTE: The mutations of the syntax tree are not reflected.
+func (sf *StructField) Source() ([]byte, error) {
+	from, to := sf.fieldRange()
+	return sourceOf(sf.facade.prog(), sf.facade.fset, from, to)
+}
+
+// fieldRange returns the range of the field, including its doc comment.
+func (sf *StructField) fieldRange() (start, end token.Pos) {
+	if !sf.node.Pos().IsValid() {
+		return sf.obj.Pos(), sf.obj.Pos()
+	}
+	start, end = sf.node.Pos(), sf.node.End()
+	if sf.node.Doc != nil && sf.node.Doc.Pos() < start {
+		start = sf.node.Doc.Pos()
+	}
+	return start, end
+}
+
+// position returns the position of pos, or the zero value if unknown.
+func position(fset *token.FileSet, pos token.Pos) token.Position {
+	if fset == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	return fset.Position(pos)
+}
+
+// sourceOf returns the original source code in the range [start, end).
+func sourceOf(prog *Program, fset *token.FileSet, start, end token.Pos) ([]byte, error) {
+	if prog == nil || fset == nil || !start.IsValid() || start == end {
+		return nil, fmt.Errorf("aster: no source code")
+	}
+	tf := fset.File(start)
+	if tf == nil || !tokenFileContainsPos(tf, end-1) {
+		return nil, fmt.Errorf("aster: no source code in the range")
+	}
+	src, err := prog.source(tf.Name())
+	if err != nil {
+		return nil, err
+	}
+	from, to := tf.Offset(start), tf.Offset(end)
+	if to > len(src) || from > to {
+		return nil, fmt.Errorf("aster: the source code of %s has changed", tf.Name())
+	}
+	return src[from:to], nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosition(t *testing.T) {
+	var src = `package test
+
+// A is a struct.
+type A struct {
+	// X is a field.
+	X int ` + "`json:\"x\"`" + `
+}
+
+const (
+	// B is grouped.
+	B = 1
+	C = 2
+)
+
+// F is a function.
+func F() {}
+`
+	prog, err := aster.LoadFile("position.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	a := prog.Lookup(aster.Typ, 0, "A")[0]
+	assert.Equal(t, "position.go:4:6", a.Position().String())
+	start, end := a.Range()
+	assert.Equal(t, "position.go:3:1", start.String())
+	assert.Equal(t, "position.go:7:2", end.String())
+	source, err := a.Source()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "// A is a struct.\ntype A struct {\n\t// X is a field.\n\tX int `json:\"x\"`\n}", string(source))
+	}
+
+	x := a.Field(0)
+	assert.Equal(t, "position.go:6:2", x.Position().String())
+	start, _ = x.Range()
+	assert.Equal(t, "position.go:5:2", start.String())
+	source, err = x.Source()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "// X is a field.\n\tX int `json:\"x\"`", string(source))
+	}
+
+	source, err = prog.Lookup(aster.Con, 0, "B")[0].Source()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "// B is grouped.\n\tB = 1", string(source))
+	}
+	source, err = prog.Lookup(aster.Fun, 0, "F")[0].Source()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "// F is a function.\nfunc F() {}", string(source))
+	}
+}
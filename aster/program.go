@@ -16,16 +16,18 @@
 package aster
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"go/types"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
 
+	"github.com/andeya/aster/internal/buildutil"
 	"github.com/andeya/aster/internal/loader"
 	"github.com/andeya/aster/tools"
 	"github.com/andeya/goutil"
@@ -67,6 +69,49 @@ type Program struct {
 	filesToUpdate map[*token.File]bool
 	// <filename, codes> Non file Sources
 	nonfileSources map[string][]byte
+
+	// patterns are the package patterns loaded in module mode,
+	// as specified by ImportPatterns() and ImportPatternsWithTests().
+	patterns     []string
+	patternTests bool
+
+	// build options of the target, as specified by SetBuildTags(),
+	// SetGOOS(), SetGOARCH() and SetCgoEnabled()
+	buildTags []string
+	buildEnv  []string
+
+	// <absolute filename, codes> in-memory contents replacing the files on disk
+	overlay map[string][]byte
+
+	// fsys is the file system to read the source code from, as specified by SetFS()
+	fsys fs.FS
+
+	// parallelism and progress, as specified by SetParallelism() and SetProgress()
+	parallelism int
+	progress    *progressTracker
+
+	// depsFromExportData loads the dependencies from the compiler export data,
+	// as specified by SetDepsFromExportData()
+	depsFromExportData bool
+
+	// cacheDir is the directory of the persistent cache of the dependencies,
+	// as specified by SetCacheDir()
+	cacheDir string
+
+	// mode is the amount of detail to load, as specified by SetLoadMode()
+	mode LoadMode
+
+	// strict makes Load fail on hard errors, as specified by SetStrict()
+	strict bool
+
+	// orphanFacades are the facades of the objects of no loaded package, such as the universe ones.
+	orphanFacades map[types.Object]*facade
+
+	// parseErrors are the syntax errors of the partial files parsed by the program.
+	parseErrors map[*ast.File]error
+
+	// srcImporter loads the new imports added by AST mutations when re-checking.
+	srcImporter types.ImporterFrom
 }
 
 // LoadFile parses the source code of a single Go file and loads a new program.
@@ -78,25 +123,16 @@ func LoadFile(filename string, src interface{}) (*Program, error) {
 	return NewProgram().AddFile(filename, src).Load()
 }
 
-// LoadDirs parses the source code of Go loaderFiles under the directories and loads a new program.
+// LoadDirs parses the source code of Go packages under the directories and loads a new program.
+//
+// The files of each directory are grouped into one package, see AddDirs.
 func LoadDirs(dirs ...string) (*Program, error) {
-	p := NewProgram()
-	for _, dir := range dirs {
-		if !filepath.IsAbs(dir) {
-			dir, _ = filepath.Abs(dir)
-		}
-		err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
-			if err != nil || f.IsDir() || !strings.HasSuffix(path, ".go") {
-				return nil
-			}
-			p.AddFile(path, nil)
-			return nil
-		})
-		if err != nil {
-			return nil, err
-		}
-	}
-	return p.Load()
+	return NewProgram().AddDirs(dirs...).Load()
+}
+
+// LoadDirsWithTests is the same as LoadDirs, but also loads the *_test.go files, see AddDirsWithTests.
+func LoadDirsWithTests(dirs ...string) (*Program, error) {
+	return NewProgram().AddDirsWithTests(dirs...).Load()
 }
 
 // LoadPkgs imports packages and loads a new program.
@@ -125,22 +161,23 @@ func LoadPkgsWithTests(pkgPath ...string) (*Program, error) {
 func NewProgram() *Program {
 	prog := new(Program)
 	prog.filesToUpdate = make(map[*token.File]bool, 128)
+	prog.mode = LoadSyntax
 	prog.conf.ParserMode = parser.ParseComments
-	// Optimization: don't type-check the bodies of functions in our
+	// Optimization: by default, don't type-check the bodies of functions in our
 	// dependencies, since we only need exported package members.
 	prog.conf.TypeCheckFuncBodies = func(p string) bool {
 		pp := strings.TrimSuffix(p, "_test")
 		for _, cp := range prog.conf.CreatePkgs {
 			if cp.Path == p || cp.Path == pp {
-				return true
+				return prog.typeCheckFuncBodies(true)
 			}
 		}
 		for k := range prog.conf.ImportPkgs {
 			if k == p || k == pp {
-				return true
+				return prog.typeCheckFuncBodies(true)
 			}
 		}
-		return false
+		return prog.typeCheckFuncBodies(false)
 	}
 	// Ideally we would just return conf.Load() here, but go/types
 	// reports certain "soft" errors that gc does not (Go issue 14596).
@@ -159,6 +196,50 @@ func (prog *Program) ErrorHandlerOfTypeChecker(errHandle func(error)) (itself *P
 	return prog
 }
 
+// SetOverlay sets the in-memory contents of files, keyed by filename,
+// which replace the contents of the files on disk when loading,
+// for both the created and imported packages.
+//
+// NOTE:
+//
+//	It should be called before AddFile to take effect on the added files;
+//	The overlaid files of the imported packages must exist on disk,
+//	since only the contents are overlaid, not the directory listing.
+func (prog *Program) SetOverlay(overlay map[string][]byte) (itself *Program) {
+	if prog.initiated {
+		return prog
+	}
+	prog.overlay = make(map[string][]byte, len(overlay))
+	for filename, content := range overlay {
+		if abs, err := filepath.Abs(filename); err == nil {
+			filename = abs
+		}
+		prog.overlay[filename] = content
+	}
+	prog.conf.Build = buildutil.OverlayContext(prog.buildContext(), prog.overlay)
+	return prog
+}
+
+// overlaid returns the in-memory contents of the file, if any.
+func (prog *Program) overlaid(filename string) ([]byte, bool) {
+	if len(prog.overlay) == 0 || filename == "" {
+		return nil, false
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, false
+	}
+	if content, ok := prog.overlay[abs]; ok {
+		return content, true
+	}
+	for name, content := range prog.overlay {
+		if sameFile(name, abs) {
+			return content, true
+		}
+	}
+	return nil, false
+}
+
 // AddFile parses the source code of a single Go source file.
 //
 // src specifies the parser input as a string, []byte, or io.Reader, and
@@ -169,27 +250,56 @@ func (prog *Program) ErrorHandlerOfTypeChecker(errHandle func(error)) (itself *P
 // if empty, rewrite to self-increasing number filename under the package name path.
 func (prog *Program) AddFile(filename string, src interface{}) (itself *Program) {
 	if !prog.initiated && prog.initialError == nil {
-		var _src interface{}
-		b, srcErr := tools.ReadSourceBytes(src)
-		if b != nil {
-			_src = b
-		}
-		f, err := prog.conf.ParseFile(filename, _src)
-		if err != nil {
-			prog.initialError = err
-		} else {
-			if filename == "" {
-				filename = autoFilename(f)
-			}
+		f, filename, ok := prog.parseFile(filename, src)
+		if ok {
 			prog.conf.CreateFromFiles(f.Name.Name, &loader.File{Filename: filename, File: f})
-			if srcErr == nil {
-				prog.nonfileSources[filename] = b
-			}
 		}
 	}
 	return prog
 }
 
+// parseFile parses the source code of a single Go source file for AddFile and AddDirs,
+// and returns the file and its filename.
+//
+// On syntax errors, the partial file is kept, and the errors are reported as diagnostics.
+// On failure, sets the initial error and returns ok=false.
+func (prog *Program) parseFile(filename string, src interface{}) (f *ast.File, _ string, ok bool) {
+	var _src interface{}
+	filename = prog.fsPath(filename)
+	if src == nil {
+		if content, ok := prog.overlaid(filename); ok {
+			src = content
+		} else if name, ok := fsName(filename); ok && prog.fsys != nil {
+			content, err := fs.ReadFile(prog.fsys, name)
+			if err != nil {
+				prog.initialError = err
+				return nil, filename, false
+			}
+			src = content
+		}
+	}
+	b, srcErr := tools.ReadSourceBytes(src)
+	if b != nil {
+		_src = b
+	}
+	f, err := prog.conf.ParseFile(filename, _src)
+	if err != nil {
+		// The package clause is required to know which package the file belongs to.
+		if f == nil || f.Name == nil || f.Name.Name == "" {
+			prog.initialError = err
+			return nil, filename, false
+		}
+		prog.setParseError(f, err)
+	}
+	if filename == "" {
+		filename = autoFilename(f)
+	}
+	if srcErr == nil {
+		prog.nonfileSources[filename] = b
+	}
+	return f, filename, true
+}
+
 // Import imports packages that will be imported from source,
 // the set of initial source packages located relative to $GOPATH.
 func (prog *Program) Import(pkgPath ...string) (itself *Program) {
@@ -225,6 +335,12 @@ func (prog *Program) ImportWithTests(pkgPath ...string) (itself *Program) {
 // On failure, returns an error.
 // It is an error if no packages were loaded.
 func (prog *Program) Load() (itself *Program, err error) {
+	return prog.LoadContext(context.Background())
+}
+
+// LoadContext is the same as Load, but stops loading and returns
+// the context's error when the context is cancelled.
+func (prog *Program) LoadContext(ctx context.Context) (itself *Program, err error) {
 	if prog.initiated {
 		return prog, errors.New("can not load two times")
 	}
@@ -239,31 +355,38 @@ func (prog *Program) Load() (itself *Program, err error) {
 			fmt.Printf("panic:%v\n%s", err, goutil.PanicTrace(1))
 		}
 	}()
-	p, err := prog.conf.Load()
+	if err = prog.initSizes(ctx); err != nil {
+		prog.initialError = err
+		return prog, prog.initialError
+	}
+	var p *loader.Program
+	if len(prog.patterns) > 0 {
+		p, err = prog.loadPatterns(ctx)
+	} else {
+		prog.conf.Context = ctx
+		prog.conf.Parallelism = prog.parallelism
+		prog.conf.BeforeTypeCheck, prog.conf.AfterTypeCheck = prog.loaderProgressHooks()
+		if prog.conf.Fset == nil {
+			prog.conf.Fset = token.NewFileSet()
+		}
+		if prog.depsFromExportData {
+			prog.conf.ImportFromExportData = newExportImporter(ctx, prog).importFrom
+		} else if prog.cacheDir != "" {
+			prog.conf.PackageCache = newDiskCache(prog)
+		}
+		p, err = prog.conf.Load()
+	}
 	if err != nil {
 		prog.initialError = err
 		return prog, prog.initialError
 	}
-	// Allow exist error
-
-	// var errpkgs []string
-	// // Report hard errors in indirectly imported packages.
-	// for _, info := range p.AllPackages {
-	// 	if containsHardErrors(info.Errors) {
-	// 		errpkgs = append(errpkgs, info.Pkg.Path())
-	// 	}
-	// }
-	// if errpkgs != nil {
-	// 	var more string
-	// 	if len(errpkgs) > 3 {
-	// 		more = fmt.Sprintf(" and %d more", len(errpkgs)-3)
-	// 		errpkgs = errpkgs[:3]
-	// 	}
-	// 	prog.initialError = fmt.Errorf("couldn't load packages due to errors: %s%s",
-	// 		strings.Join(errpkgs, ", "), more)
-	// 	return prog, prog.initialError
-	// }
-	return prog.convert(p), nil
+	prog.convert(p)
+	// Allow the errors, which can be obtained by Diagnostics, unless in strict mode.
+	if err = prog.checkStrict(); err != nil {
+		prog.initialError = err
+		return prog, prog.initialError
+	}
+	return prog, nil
 }
 
 // MustLoad is the same as Load(), but panic when error occur.
@@ -282,11 +405,17 @@ func (prog *Program) convert(p *loader.Program) (itself *Program) {
 
 	var solved = make(map[*loader.PackageInfo]*PackageInfo, len(p.AllPackages))
 	for _, info := range p.Created {
+		prog.trim(info, true)
 		newInfo := newPackageInfo(prog, info)
+		if errs := newInfo.parseErrors(); len(errs) > 0 {
+			newInfo.Errors = append(errs, newInfo.Errors...)
+			newInfo.transitivelyErrorFree = false
+		}
 		solved[info] = newInfo
 		prog.created = append(prog.created, newInfo)
 	}
 	for k, info := range p.Imported {
+		prog.trim(info, true)
 		newInfo := newPackageInfo(prog, info)
 		solved[info] = newInfo
 		prog.imported[k] = newInfo
@@ -295,6 +424,7 @@ func (prog *Program) convert(p *loader.Program) (itself *Program) {
 		if newInfo, ok := solved[info]; ok {
 			prog.allPackages[k] = newInfo
 		} else {
+			prog.trim(info, false)
 			newInfo := newPackageInfo(prog, info)
 			solved[info] = newInfo
 			prog.allPackages[k] = newInfo
@@ -325,6 +455,9 @@ func (prog *Program) InitialPackages() []*PackageInfo {
 // specified package.
 // NOTE: return nil, if the package does not exist.
 func (prog *Program) Package(path string) *PackageInfo {
+	if info, ok := prog.imported[path]; ok {
+		return info
+	}
 	for k, v := range prog.allPackages {
 		if k.Path() == path {
 			return v
@@ -359,9 +492,44 @@ func (prog *Program) source(filename string) ([]byte, error) {
 	if ok {
 		return src, nil
 	}
+	return prog.fileSource(filename)
+}
+
+// fileSource reads the file from the overlay, the file system set by SetFS, or the disk,
+// ignoring the source given in memory to AddFile.
+func (prog *Program) fileSource(filename string) ([]byte, error) {
+	if src, ok := prog.overlaid(filename); ok {
+		return src, nil
+	}
+	if name, ok := fsName(filename); ok && prog.fsys != nil {
+		return fs.ReadFile(prog.fsys, name)
+	}
 	return tools.ReadSource(filename, nil)
 }
 
+// setParseError records the syntax error of the partial file, or clears it if err is nil.
+func (prog *Program) setParseError(f *ast.File, err error) {
+	if err == nil {
+		delete(prog.parseErrors, f)
+		return
+	}
+	if prog.parseErrors == nil {
+		prog.parseErrors = make(map[*ast.File]error)
+	}
+	prog.parseErrors[f] = err
+}
+
+// parseErrors returns the syntax errors of the package's files parsed by the program.
+func (p *PackageInfo) parseErrors() []error {
+	var errs []error
+	for _, f := range p.Files {
+		if err, ok := p.prog.parseErrors[f.File]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
 func containsHardErrors(errors []error) bool {
 	for _, err := range errors {
 		if err, ok := err.(types.Error); ok && err.Soft {
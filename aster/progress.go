@@ -0,0 +1,154 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"go/types"
+	"strconv"
+	"sync"
+
+	"github.com/andeya/aster/internal/loader"
+	"github.com/andeya/aster/internal/packages"
+)
+
+// ProgressEvent is the kind of loading progress event.
+type ProgressEvent int
+
+const (
+	// PackageStarted is reported before a package is type-checked.
+	PackageStarted ProgressEvent = iota
+	// PackageFinished is reported after a package is type-checked.
+	PackageFinished
+)
+
+// String returns the event name.
+func (e ProgressEvent) String() string {
+	switch e {
+	case PackageStarted:
+		return "started"
+	case PackageFinished:
+		return "finished"
+	}
+	return "ProgressEvent(" + strconv.Itoa(int(e)) + ")"
+}
+
+// Progress is the loading progress of a package.
+type Progress struct {
+	Event    ProgressEvent
+	Path     string // the package path
+	Errors   int    // the number of errors of the package, when finished
+	Started  int    // the number of packages started so far
+	Finished int    // the number of packages finished so far
+	// ErrorsTotal is the number of errors of the finished packages so far.
+	ErrorsTotal int
+}
+
+// SetParallelism sets the maximum number of packages type-checked concurrently.
+// If zero or negative, the number is unlimited.
+func (prog *Program) SetParallelism(n int) (itself *Program) {
+	if !prog.initiated {
+		prog.parallelism = n
+	}
+	return prog
+}
+
+// SetProgress sets the callback reporting the loading progress.
+// NOTE: The calls are serialized, but are made from the loading goroutines,
+// so the callback should return quickly.
+func (prog *Program) SetProgress(fn func(Progress)) (itself *Program) {
+	if !prog.initiated {
+		if fn == nil {
+			prog.progress = nil
+		} else {
+			prog.progress = &progressTracker{fn: fn, started: make(map[interface{}]bool)}
+		}
+	}
+	return prog
+}
+
+// progressTracker counts the packages and serializes the progress callbacks.
+type progressTracker struct {
+	mu          sync.Mutex
+	fn          func(Progress)
+	started     map[interface{}]bool
+	numStarted  int
+	numFinished int
+	numErrors   int
+}
+
+func (t *progressTracker) start(key interface{}, path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started[key] = true
+	t.numStarted++
+	t.fn(Progress{
+		Event:       PackageStarted,
+		Path:        path,
+		Started:     t.numStarted,
+		Finished:    t.numFinished,
+		ErrorsTotal: t.numErrors,
+	})
+}
+
+func (t *progressTracker) finish(key interface{}, path string, errors int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started[key] {
+		return
+	}
+	delete(t.started, key)
+	t.numFinished++
+	t.numErrors += errors
+	t.fn(Progress{
+		Event:       PackageFinished,
+		Path:        path,
+		Errors:      errors,
+		Started:     t.numStarted,
+		Finished:    t.numFinished,
+		ErrorsTotal: t.numErrors,
+	})
+}
+
+// loaderProgressHooks returns the type-checking hooks of the GOPATH loader.
+func (prog *Program) loaderProgressHooks() (before, after func(*loader.PackageInfo, []*loader.File)) {
+	t := prog.progress
+	if t == nil {
+		return nil, nil
+	}
+	before = func(info *loader.PackageInfo, _ []*loader.File) {
+		if info.Pkg != types.Unsafe {
+			t.start(info, info.Pkg.Path())
+		}
+	}
+	after = func(info *loader.PackageInfo, _ []*loader.File) {
+		t.finish(info, info.Pkg.Path(), len(info.Errors))
+	}
+	return
+}
+
+// packagesProgressHooks returns the type-checking hooks of the module loader.
+func (prog *Program) packagesProgressHooks() (before, after func(*packages.Package)) {
+	t := prog.progress
+	if t == nil {
+		return nil, nil
+	}
+	before = func(pkg *packages.Package) {
+		t.start(pkg, pkg.PkgPath)
+	}
+	after = func(pkg *packages.Package) {
+		t.finish(pkg, pkg.PkgPath, len(pkg.Errors))
+	}
+	return
+}
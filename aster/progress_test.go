@@ -0,0 +1,73 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadContext(t *testing.T) {
+	var src = "package test\n\nimport \"strings\"\n\nvar X = strings.ToUpper(\"x\")\n"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := aster.NewProgram().AddFile("progress.go", src).LoadContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": src,
+	})
+	_, err = aster.NewProgram().SetWorkDir(dir).ImportPatterns("./...").LoadContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestProgress(t *testing.T) {
+	var src = "package test\n\nimport \"strings\"\n\nvar X = strings.ToUpper(\"x\")\n\nvar Y = Undefined\n"
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": src,
+	})
+	for _, prog := range []*aster.Program{
+		aster.NewProgram().AddFile("progress.go", src),
+		aster.NewProgram().SetWorkDir(dir).ImportPatterns("./a"),
+	} {
+		var (
+			events   []aster.Progress
+			finished = make(map[string]int)
+		)
+		prog.SetParallelism(1).SetProgress(func(p aster.Progress) {
+			events = append(events, p)
+			if p.Event == aster.PackageFinished {
+				finished[p.Path] = p.Errors
+			}
+			// Only one package is type-checked at a time.
+			assert.LessOrEqual(t, p.Started-p.Finished, 1)
+		})
+		_, err := prog.LoadContext(context.Background())
+		if !assert.NoError(t, err) || !assert.NotEmpty(t, events) {
+			continue
+		}
+		last := events[len(events)-1]
+		assert.Equal(t, aster.PackageFinished, last.Event)
+		assert.Equal(t, last.Started, last.Finished)
+		assert.Equal(t, 1, last.ErrorsTotal)
+		assert.Contains(t, finished, "strings")
+		assert.Equal(t, 0, finished["strings"])
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/andeya/aster/internal/buildutil"
+)
+
+// Recheck re-type-checks the mutated ASTs of the created and imported packages,
+// and rebuilds their facades.
+//
+// The packages are re-checked in dependency order, so that a package
+// sees the fresh types of the initial packages it imports.
+// NOTE:
+//
+//	The facades obtained before Recheck are stale and should not be used any more;
+//	Returns the first error of the packages, if any.
+func (prog *Program) Recheck() (first error) {
+	if !prog.initiated {
+		return errors.New("can not recheck before loading")
+	}
+	if prog.initialError != nil {
+		return prog.initialError
+	}
+	pkgs := prog.InitialPackages()
+	byPath := make(map[string]*PackageInfo, len(pkgs))
+	for _, pkg := range pkgs {
+		byPath[pkg.Pkg.Path()] = pkg
+	}
+	rechecked := make(map[string]*types.Package, len(pkgs))
+	visiting := make(map[*PackageInfo]bool, len(pkgs))
+	var visit func(pkg *PackageInfo)
+	visit = func(pkg *PackageInfo) {
+		if visiting[pkg] {
+			return
+		}
+		visiting[pkg] = true
+		for _, imp := range pkg.Pkg.Imports() {
+			if dep, ok := byPath[imp.Path()]; ok && dep != pkg {
+				visit(dep)
+			}
+		}
+		if err := pkg.recheck(rechecked); err != nil && first == nil {
+			first = err
+		}
+		if pkg.importable {
+			rechecked[pkg.Pkg.Path()] = pkg.Pkg
+		}
+	}
+	// The importable packages first, then the created ones.
+	for _, pkg := range pkgs {
+		if pkg.importable {
+			visit(pkg)
+		}
+	}
+	for _, pkg := range pkgs {
+		visit(pkg)
+	}
+	return first
+}
+
+// Recheck re-type-checks the mutated ASTs of the package, and rebuilds its facades.
+// NOTE:
+//
+//	The facades obtained before Recheck are stale and should not be used any more;
+//	The packages importing it are not re-checked, use Program.Recheck for that.
+func (p *PackageInfo) Recheck() error {
+	if p.Pkg == types.Unsafe {
+		return nil
+	}
+	return p.recheck(nil)
+}
+
+// recheck re-type-checks the package, resolving its imports from
+// rechecked first, then from the packages it imported last time.
+func (p *PackageInfo) recheck(rechecked map[string]*types.Package) error {
+	prog := p.prog
+	oldPkg := p.Pkg
+	imports := make(map[string]*types.Package, len(oldPkg.Imports()))
+	for _, imp := range oldPkg.Imports() {
+		imports[imp.Path()] = imp
+	}
+
+	info := types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	errs := p.parseErrors()
+	initial := prog.isInitial(p)
+	tc := prog.conf.TypeChecker
+	// Like Load, type-check the bodies of functions as the load mode requires.
+	tc.IgnoreFuncBodies = !prog.typeCheckFuncBodies(initial)
+	tc.Importer = importerFunc(func(path string) (*types.Package, error) {
+		if path == "unsafe" {
+			return types.Unsafe, nil
+		}
+		if pkg, ok := rechecked[path]; ok {
+			return pkg, nil
+		}
+		if pkg, ok := imports[path]; ok {
+			return pkg, nil
+		}
+		if info := prog.Package(path); info != nil && info.importable {
+			return info.Pkg, nil
+		}
+		// A new import that the program never loaded.
+		imported, err := prog.sourceImporter().ImportFrom(path, p.dir(), 0)
+		if err != nil {
+			return nil, err
+		}
+		prog.allPackages[imported] = &PackageInfo{
+			prog:       prog,
+			Pkg:        imported,
+			importable: true,
+			Files:      make([]*File, 0),
+		}
+		return imported, nil
+	})
+	userErr := tc.Error
+	tc.Error = func(err error) {
+		errs = append(errs, err)
+		if userErr != nil {
+			userErr(err)
+		}
+	}
+
+	files := make([]*ast.File, len(p.Files))
+	for i, f := range p.Files {
+		files[i] = f.File
+	}
+	pkg := types.NewPackage(oldPkg.Path(), oldPkg.Name())
+	// Ignore the returned (first) error since we
+	// already collect them all in the errs.
+	_ = types.NewChecker(&tc, prog.fset, pkg, &info).Files(files)
+
+	delete(prog.allPackages, oldPkg)
+	prog.allPackages[pkg] = p
+	p.Pkg = pkg
+	if prog.mode&NeedTypesInfo == 0 {
+		trimTypesInfo(&info)
+	}
+	p.info = info
+	p.Errors = errs
+	p.transitivelyErrorFree = len(errs) == 0
+	for _, imp := range pkg.Imports() {
+		if dep, ok := prog.allPackages[imp]; ok && !dep.transitivelyErrorFree {
+			p.transitivelyErrorFree = false
+		}
+	}
+	if p.checked {
+		for _, f := range p.Files {
+			f.facades = make([]*facade, 0, len(f.facades))
+			f.decls = nil
+		}
+		p.check()
+	}
+
+	if len(errs) > 0 && containsHardErrors(errs) {
+		var msgs []string
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		return fmt.Errorf("recheck %s: %s", pkg.Path(), strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// sourceImporter returns the importer loading the packages
+// which were not loaded by the program from source.
+func (prog *Program) sourceImporter() types.ImporterFrom {
+	if prog.srcImporter == nil {
+		prog.srcImporter = &srcImporter{
+			prog:     prog,
+			packages: make(map[string]*types.Package),
+		}
+	}
+	return prog.srcImporter
+}
+
+// srcImporter imports the packages from source through the program's build context,
+// so that the build tags, target, overlay and file system of the program are respected.
+// The importable packages loaded by the program are reused.
+type srcImporter struct {
+	prog     *Program
+	packages map[string]*types.Package // nil while importing, to detect cycles
+}
+
+func (imp *srcImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, ".", 0)
+}
+
+func (imp *srcImporter) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	prog := imp.prog
+	bp, err := prog.buildContext().Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := imp.packages[bp.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through package %q", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+	if info := prog.Package(bp.ImportPath); info != nil && info.importable {
+		return info.Pkg, nil
+	}
+	imp.packages[bp.ImportPath] = nil
+
+	var files []*ast.File
+	for _, names := range [][]string{bp.GoFiles, bp.CgoFiles} {
+		for _, name := range names {
+			f, err := buildutil.ParseFile(prog.fset, prog.buildContext(), nil, bp.Dir, name, 0)
+			if err != nil {
+				delete(imp.packages, bp.ImportPath)
+				return nil, err
+			}
+			files = append(files, f)
+		}
+	}
+	conf := types.Config{
+		Importer:         imp,
+		Sizes:            prog.conf.TypeChecker.Sizes,
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		// Type-check the whole package, and report the first error.
+		Error: func(error) {},
+	}
+	pkg, err := conf.Check(bp.ImportPath, prog.fset, files, nil)
+	if err != nil {
+		delete(imp.packages, bp.ImportPath)
+		return nil, fmt.Errorf("type-checking package %q failed (%v)", bp.ImportPath, err)
+	}
+	imp.packages[bp.ImportPath] = pkg
+	return pkg, nil
+}
+
+// dir returns the directory of the package, or "" if unknown.
+func (p *PackageInfo) dir() string {
+	for _, f := range p.Files {
+		if f.Filename != "" {
+			dir, _ := filepath.Abs(filepath.Dir(f.Filename))
+			return dir
+		}
+	}
+	return ""
+}
+
+// An importerFunc is an implementation of the single-method
+// types.Importer interface based on a function value.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
@@ -0,0 +1,83 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecheck(t *testing.T) {
+	var src = `package test
+type S struct{
+	A string
+}
+func F() string {
+	return ""
+}
+`
+	prog, err := aster.LoadFile("../_out/recheck.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := prog.Package("test")
+	f := pkg.Lookup(aster.Fun, aster.Signature, "F")[0]
+	assert.NoError(t, pkg.Files[0].AddImport("strings"))
+	assert.NoError(t, f.CoverBody(`return strings.ToUpper(S{}.A)`))
+	assert.NoError(t, prog.Recheck())
+	assert.Empty(t, pkg.Errors)
+	assert.Len(t, pkg.Lookup(aster.Typ, aster.Struct, "S"), 1)
+	assert.Len(t, pkg.Lookup(aster.Fun, aster.Signature, "F"), 1)
+
+	s := pkg.Lookup(aster.Typ, aster.Struct, "S")[0]
+	a, _ := s.FieldByName("A")
+	a.Tags().Set(&aster.Tag{Key: "json", Name: "a"})
+	assert.NoError(t, pkg.Recheck())
+	s = pkg.Lookup(aster.Typ, aster.Struct, "S")[0]
+	assert.Equal(t, `json:"a"`, s.Underlying().(interface{ Tag(int) string }).Tag(0))
+
+	f = pkg.Lookup(aster.Fun, aster.Signature, "F")[0]
+	assert.NoError(t, f.CoverBody(`return undefined`))
+	assert.Error(t, prog.Recheck())
+	assert.NotEmpty(t, pkg.Errors)
+
+	_, err = prog.Load()
+	assert.Error(t, err)
+}
+
+func TestRecheckNewImport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"example.com/a/a.go":     {Data: []byte("package a\n\nfunc A() string { return \"\" }\n")},
+		"example.com/b/b_foo.go": {Data: []byte("//go:build foo\n\npackage b\n\nconst B = 1\n")},
+		"example.com/b/b_bar.go": {Data: []byte("//go:build !foo\n\npackage b\n\nconst B = \"\"\n")},
+	}
+	prog, err := aster.NewProgram().SetFS(fsys).SetBuildTags("foo").Import("example.com/a").Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	// The new import is loaded from fsys, with the build tags.
+	pkg := prog.Package("example.com/a")
+	assert.NoError(t, pkg.Files[0].AddImport("example.com/b"))
+	a := pkg.Lookup(aster.Fun, aster.Signature, "A")[0]
+	assert.NoError(t, a.CoverBody(`var i int = b.B; _ = i; return ""`))
+	assert.NoError(t, prog.Recheck())
+	assert.Empty(t, pkg.Errors)
+	if b := prog.Package("example.com/b"); assert.NotNil(t, b) {
+		assert.Equal(t, "untyped int", b.Pkg.Scope().Lookup("B").Type().String())
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// A Reference is a use of a declared object, such as in an expression,
+// a selector expression or a key of a composite literal.
+type Reference struct {
+	// File is the file of the use.
+	File *File
+	// Ident is the identifier of the use.
+	Ident *ast.Ident
+	// Position is the position of the identifier.
+	Position token.Position
+	// Enclosing is the innermost package-level or function facade whose declaration contains the use,
+	// such as the function rather than its local variable, or nil if none.
+	Enclosing Facade
+}
+
+// References returns the uses of the object across all the loaded packages,
+// ordered by filename and position.
+//
+// The uses of the instances of a generic type or function are included.
+// NOTE: Only the packages with syntax trees are searched, that is the dependencies require NeedDepsSyntax,
+// and the uses in the function bodies which are not type-checked are not found, see LoadMode.
+func (fa *facade) References() []Reference {
+	if prog := fa.prog(); prog != nil {
+		return prog.references(fa.obj)
+	}
+	return nil
+}
+
+// References returns the uses of the field across all the loaded packages,
+// ordered by filename and position.
+// NOTE: See Facade.References.
+func (sf *StructField) References() []Reference {
+	if prog := sf.facade.prog(); prog != nil {
+		return prog.references(sf.obj)
+	}
+	return nil
+}
+
+// references returns the uses of the object in all the packages.
+func (prog *Program) references(obj types.Object) []Reference {
+	var refs []Reference
+	for _, pkg := range prog.allPackages {
+		for ident, used := range pkg.info.Uses {
+			if used != obj && originObj(used) != obj {
+				continue
+			}
+			ref := Reference{
+				Ident:    ident,
+				Position: prog.fset.Position(ident.Pos()),
+			}
+			if file := pkg.fileOf(ident.Pos()); file != nil {
+				ref.File = file
+				if enclosing := file.enclosingFacade(ident.Pos()); enclosing != nil {
+					ref.Enclosing = enclosing
+				}
+			}
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Position.Filename != refs[j].Position.Filename {
+			return refs[i].Position.Filename < refs[j].Position.Filename
+		}
+		return refs[i].Position.Offset < refs[j].Position.Offset
+	})
+	return refs
+}
+
+// originObj returns the generic object of an object of an instance, or the object itself.
+func originObj(obj types.Object) types.Object {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		return o.Origin()
+	}
+	return obj
+}
+
+// fileOf returns the file containing the position, or nil if not found.
+func (p *PackageInfo) fileOf(pos token.Pos) *File {
+	for _, f := range p.Files {
+		if f.Pos() <= pos && pos <= f.End() {
+			return f
+		}
+	}
+	return nil
+}
+
+// enclosingFacade returns the innermost package-level or function facade of the file
+// whose declaration contains the position, or nil if none.
+func (f *File) enclosingFacade(pos token.Pos) *facade {
+	idx := f.declIndex()
+	for fa := idx.innermost(pos); fa != nil; fa = idx.parents[fa] {
+		if _, isFunc := fa.obj.(*types.Func); isFunc || fa.IsPackageLevel() {
+			return fa
+		}
+	}
+	return nil
+}
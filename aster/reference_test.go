@@ -0,0 +1,68 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferences(t *testing.T) {
+	var src = `package test
+
+type A struct{ X int }
+
+type G[T any] struct{ V T }
+
+func F() int {
+	a := A{X: 1}
+	return a.X
+}
+
+var B = A{}
+
+var C = G[int]{V: 1}.V
+`
+	prog, err := aster.LoadFile("references.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	a := prog.Lookup(aster.Typ, 0, "A")[0]
+	refs := a.References()
+	if assert.Len(t, refs, 2) {
+		assert.Equal(t, "references.go:8:7", refs[0].Position.String())
+		// the function rather than the local variable
+		assert.Equal(t, "F", refs[0].Enclosing.Name())
+		assert.Equal(t, "references.go:12:9", refs[1].Position.String())
+		assert.Equal(t, "B", refs[1].Enclosing.Name())
+		assert.Equal(t, "references.go", refs[1].File.Filename)
+	}
+
+	refs = a.Field(0).References()
+	if assert.Len(t, refs, 2) {
+		// the key of the composite literal
+		assert.Equal(t, "references.go:8:9", refs[0].Position.String())
+		// the selector expression
+		assert.Equal(t, "references.go:9:11", refs[1].Position.String())
+		assert.Equal(t, "F", refs[1].Enclosing.Name())
+	}
+
+	g := prog.Lookup(aster.Typ, 0, "G")[0]
+	assert.Len(t, g.References(), 1)
+	assert.Len(t, g.Field(0).References(), 2)
+	assert.Empty(t, prog.Lookup(aster.Var, 0, "C")[0].References())
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"os"
+	"path/filepath"
+
+	"github.com/andeya/aster/internal/loader"
+)
+
+// ReloadFiles re-parses the changed files from the overlay or file system, and re-checks
+// only the packages containing them and their reverse dependencies.
+//
+// A file that no longer exists is removed from its package;
+// a new file is added to the loaded package in the same directory with the same name.
+// A file added with its source in memory is reloaded from the overlay or file system too,
+// and it is an error if it is not found there.
+// NOTE:
+//
+//	The facades of the untouched packages are kept;
+//	The facades of the re-checked packages obtained before are stale.
+func (prog *Program) ReloadFiles(filenames ...string) error {
+	if !prog.initiated {
+		return errors.New("can not reload before loading")
+	}
+	if prog.initialError != nil {
+		return prog.initialError
+	}
+
+	type change struct {
+		pkg  *PackageInfo
+		file *File // nil if the file is new
+		ast  *ast.File
+	}
+	var changes []change
+	for _, filename := range filenames {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return err
+		}
+		pkg, file := prog.findFile(abs)
+		src, err := prog.fileSource(abs)
+		if err != nil {
+			if os.IsNotExist(err) && file != nil {
+				if _, ok := prog.nonfileSources[file.Filename]; ok {
+					return fmt.Errorf("can not reload %s: its source was given in memory, and it is not in the overlay or file system", filename)
+				}
+				changes = append(changes, change{pkg: pkg, file: file})
+				continue
+			}
+			return err
+		}
+		if file != nil {
+			if _, ok := prog.nonfileSources[file.Filename]; ok {
+				prog.nonfileSources[file.Filename] = src
+			}
+		}
+		f, err := parser.ParseFile(prog.fset, filename, src, prog.conf.ParserMode)
+		if err != nil && (f == nil || f.Name == nil || f.Name.Name == "") {
+			return err
+		}
+		// Keep the partial file, whose syntax errors are reported by re-checking.
+		prog.setParseError(f, err)
+		if pkg == nil {
+			pkg = prog.findPackageByDir(filepath.Dir(abs), f.Name.Name)
+			if pkg == nil {
+				return fmt.Errorf("can not reload %s: no package loaded in its directory", filename)
+			}
+		}
+		changes = append(changes, change{pkg: pkg, file: file, ast: f})
+	}
+
+	// Compute the reverse dependencies before the packages are replaced.
+	importedBy := make(map[*types.Package][]*PackageInfo, len(prog.allPackages))
+	for _, info := range prog.allPackages {
+		if len(info.Files) == 0 {
+			continue
+		}
+		for _, imp := range info.Pkg.Imports() {
+			importedBy[imp] = append(importedBy[imp], info)
+		}
+	}
+
+	affected := make(map[*PackageInfo]bool)
+	var markAffected func(*PackageInfo)
+	markAffected = func(p *PackageInfo) {
+		if affected[p] {
+			return
+		}
+		affected[p] = true
+		for _, client := range importedBy[p.Pkg] {
+			markAffected(client)
+		}
+	}
+	for _, c := range changes {
+		switch {
+		case c.ast == nil:
+			prog.setParseError(c.file.File, nil)
+			c.pkg.removeFile(c.file)
+		case c.file == nil:
+			c.pkg.appendFile(c.ast)
+		default:
+			prog.setParseError(c.file.File, nil)
+			c.pkg.replaceFile(c.file, c.ast)
+		}
+		markAffected(c.pkg)
+	}
+
+	// Re-check the affected packages in dependency order.
+	var first error
+	rechecked := make(map[string]*types.Package, len(affected))
+	visited := make(map[*PackageInfo]bool, len(affected))
+	var visit func(*PackageInfo)
+	visit = func(p *PackageInfo) {
+		if visited[p] {
+			return
+		}
+		visited[p] = true
+		for _, imp := range p.Pkg.Imports() {
+			if dep, ok := prog.allPackages[imp]; ok && affected[dep] {
+				visit(dep)
+			}
+		}
+		if err := p.recheck(rechecked); err != nil && first == nil {
+			first = err
+		}
+		if p.importable {
+			rechecked[p.Pkg.Path()] = p.Pkg
+		}
+	}
+	for p := range affected {
+		if p.importable {
+			visit(p)
+		}
+	}
+	for p := range affected {
+		visit(p)
+	}
+	return first
+}
+
+// findFile returns the file with the absolute filename, and the package it belongs to.
+func (prog *Program) findFile(abs string) (*PackageInfo, *File) {
+	for _, pkg := range prog.allPackages {
+		for _, f := range pkg.Files {
+			if filename, err := filepath.Abs(f.Filename); err == nil && sameFile(filename, abs) {
+				return pkg, f
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findPackageByDir returns the loaded package with the name in the directory.
+func (prog *Program) findPackageByDir(dir string, name string) *PackageInfo {
+	for _, pkg := range prog.allPackages {
+		if pkg.Pkg.Name() == name && pkg.dir() != "" && sameFile(pkg.dir(), dir) {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// isInitial reports whether the package is a created or imported one.
+func (prog *Program) isInitial(p *PackageInfo) bool {
+	for _, pkg := range prog.created {
+		if pkg == p {
+			return true
+		}
+	}
+	for _, pkg := range prog.imported {
+		if pkg == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PackageInfo) replaceFile(file *File, f *ast.File) {
+	for _, lf := range p.loaderFiles {
+		if lf.File == file.File {
+			lf.File = f
+		}
+	}
+	file.File = f
+}
+
+func (p *PackageInfo) appendFile(f *ast.File) {
+	filename := p.prog.fset.Position(f.Pos()).Filename
+	p.loaderFiles = append(p.loaderFiles, &loader.File{Filename: filename, File: f})
+	p.Files = append(p.Files, &File{
+		Filename:    filename,
+		File:        f,
+		PackageInfo: p,
+		facades:     make([]*facade, 0),
+	})
+}
+
+func (p *PackageInfo) removeFile(file *File) {
+	for i, lf := range p.loaderFiles {
+		if lf.File == file.File {
+			p.loaderFiles = append(p.loaderFiles[:i:i], p.loaderFiles[i+1:]...)
+			break
+		}
+	}
+	for i, f := range p.Files {
+		if f == file {
+			p.Files = append(p.Files[:i:i], p.Files[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, code := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(code), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReloadFiles(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nfunc A() int { return 1 }\n",
+		"b/b.go": "package b\n\nimport \"example.com/m/a\"\n\nvar B = a.A()\n",
+		"c/c.go": "package c\n\nconst C = 1\n",
+	})
+	prog, err := aster.NewProgram().SetWorkDir(dir).ImportPatterns("./...").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := prog.Package("example.com/m/c").Lookup(aster.Con, 0, "C")[0]
+	b := prog.Package("example.com/m/b").Lookup(aster.Var, 0, "B")[0]
+	assert.Equal(t, "int", b.Object().Type().String())
+
+	err = os.WriteFile(filepath.Join(dir, "a/a.go"), []byte("package a\n\nfunc A() string { return \"\" }\n"), 0666)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "a/a2.go"), []byte("package a\n\nfunc A2() {}\n"), 0666)
+	assert.NoError(t, err)
+	assert.NoError(t, prog.ReloadFiles(filepath.Join(dir, "a/a.go"), filepath.Join(dir, "a/a2.go")))
+
+	a := prog.Package("example.com/m/a")
+	assert.Len(t, a.Files, 2)
+	assert.Len(t, a.Lookup(aster.Fun, 0, "A2"), 1)
+	b = prog.Package("example.com/m/b").Lookup(aster.Var, 0, "B")[0]
+	assert.Equal(t, "string", b.Object().Type().String())
+	assert.Equal(t, c, prog.Package("example.com/m/c").Lookup(aster.Con, 0, "C")[0])
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "a/a2.go")))
+	assert.NoError(t, prog.ReloadFiles(filepath.Join(dir, "a/a2.go")))
+	assert.Len(t, a.Files, 1)
+	assert.Empty(t, a.Lookup(aster.Fun, 0, "A2"))
+
+	// A half-typed file keeps the declarations that parsed.
+	err = os.WriteFile(filepath.Join(dir, "a/a.go"), []byte("package a\n\nfunc A() string { return \"\" }\n\nfunc A3() {\n\tif\n}\n"), 0666)
+	assert.NoError(t, err)
+	assert.Error(t, prog.ReloadFiles(filepath.Join(dir, "a/a.go")))
+	assert.Len(t, a.Lookup(aster.Fun, 0, "A"), 1)
+	assert.Len(t, a.Lookup(aster.Fun, 0, "A3"), 1)
+	if diags := a.Diagnostics(); assert.NotEmpty(t, diags) {
+		assert.Equal(t, aster.ParseSource, diags[0].Source)
+	}
+	err = os.WriteFile(filepath.Join(dir, "a/a.go"), []byte("package a\n\nfunc A() string { return \"\" }\n"), 0666)
+	assert.NoError(t, err)
+	assert.NoError(t, prog.ReloadFiles(filepath.Join(dir, "a/a.go")))
+	assert.Empty(t, a.Diagnostics())
+}
+
+func TestReloadNonfileSource(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "m.go")
+	prog, err := aster.LoadFile(filename, "package m\n\nconst M = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, prog.ReloadFiles(filename))
+	assert.Len(t, prog.Lookup(aster.Con, 0, "M"), 1)
+
+	err = os.WriteFile(filename, []byte("package m\n\nconst M2 = 2\n"), 0666)
+	assert.NoError(t, err)
+	assert.NoError(t, prog.ReloadFiles(filename))
+	assert.Empty(t, prog.Lookup(aster.Con, 0, "M"))
+	if m2 := prog.Lookup(aster.Con, 0, "M2"); assert.Len(t, m2, 1) {
+		assert.Equal(t, "const M2 = 2", m2[0].String())
+	}
+}
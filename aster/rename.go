@@ -0,0 +1,398 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Rename renames the declaration and all its references across the program,
+// including the references of the struct fields embedding the type.
+//
+// It returns an error without changing anything, if the new name would:
+// conflict with a name declared in the same scope, the same method set or the same file;
+// be shadowed at a reference, or shadow the references of another object of the same name;
+// break the implementation of an interface by a type;
+// unexport an object referenced by other packages.
+// It also returns an error, if some references may not be found,
+// since the function bodies of a package which may refer to the object are not type-checked, see LoadMode.
+// NOTE: The types are not updated until Recheck, so that Object().Name() is still the old name.
+func (fa *facade) Rename(newName string) error {
+	if fa.ident == nil || fa.pkg == nil {
+		return fmt.Errorf("aster: can not rename %s, which has no syntax", fa.obj.Name())
+	}
+	oldName := fa.ident.Name
+	if newName == oldName {
+		return nil
+	}
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return fmt.Errorf("aster: can not rename %s to %q, which is not a valid identifier", oldName, newName)
+	}
+	r := &renamer{prog: fa.pkg.prog, obj: fa.obj, from: oldName, to: newName}
+	if err := r.check(); err != nil {
+		return err
+	}
+	fa.ident.Name = newName
+	for _, ref := range r.refs {
+		ref.Ident.Name = newName
+	}
+	return nil
+}
+
+// renamer checks the conflicts of renaming an object.
+type renamer struct {
+	prog     *Program
+	obj      types.Object
+	from, to string
+	refs     []Reference
+}
+
+// check collects the references to rename and reports the first conflict.
+func (r *renamer) check() error {
+	if err := r.checkUses(); err != nil {
+		return err
+	}
+	r.refs = r.prog.references(r.obj)
+	var embedding []*types.Var
+	if tn, ok := r.obj.(*types.TypeName); ok {
+		// The embedded fields are named after the type.
+		embedding = r.prog.embeddingFields(tn)
+		for _, field := range embedding {
+			r.refs = append(r.refs, r.prog.references(field)...)
+		}
+	}
+	if !token.IsExported(r.to) && r.obj.Exported() {
+		for _, ref := range r.refs {
+			if ref.File != nil && ref.File.PackageInfo.Pkg != r.obj.Pkg() {
+				return r.errorf(ref.Position, "it is referenced by package %s, but %s is unexported",
+					ref.File.PackageInfo.Pkg.Path(), r.to)
+			}
+		}
+	}
+	// The renamed embedded fields must not conflict in their structs.
+	for _, field := range embedding {
+		fr := &renamer{prog: r.prog, obj: field, from: r.from, to: r.to}
+		if err := fr.checkSelectable(); err != nil {
+			return err
+		}
+	}
+	if r.obj.Parent() == nil {
+		return r.checkSelectable()
+	}
+	return r.checkLexical()
+}
+
+// checkUses returns an error if the uses of the object may be missing in a package,
+// which is the package of the object or imports it, directly or not.
+func (r *renamer) checkUses() error {
+	seen := make(map[*types.Package]bool)
+	for pkg, p := range r.prog.allPackages {
+		if len(p.Files) == 0 || !importsPackage(pkg, r.obj.Pkg(), seen) {
+			continue
+		}
+		if p.info.Uses == nil || !r.prog.typeCheckFuncBodies(r.prog.isInitial(p)) {
+			return fmt.Errorf("aster: can not rename %s to %s: the function bodies of package %s are not type-checked, see LoadMode",
+				r.from, r.to, pkg.Path())
+		}
+	}
+	return nil
+}
+
+// importsPackage reports whether pkg is dep or imports it, directly or not.
+// seen caches the results of the visited packages.
+func importsPackage(pkg, dep *types.Package, seen map[*types.Package]bool) bool {
+	if pkg == dep {
+		return true
+	}
+	if result, ok := seen[pkg]; ok {
+		return result
+	}
+	seen[pkg] = false // breaks the cycles
+	for _, imp := range pkg.Imports() {
+		if importsPackage(imp, dep, seen) {
+			seen[pkg] = true
+			return true
+		}
+	}
+	return false
+}
+
+// checkLexical checks the renaming of an object declared in a lexical scope.
+func (r *renamer) checkLexical() error {
+	scope := r.obj.Parent()
+	if prev := scope.Lookup(r.to); prev != nil {
+		return r.errorf(r.prog.fset.Position(prev.Pos()), "it conflicts with %s in the same scope", prev)
+	}
+	if scope == r.obj.Pkg().Scope() {
+		// The package-level names conflict with the imports of any file.
+		for i := 0; i < scope.NumChildren(); i++ {
+			if prev := scope.Child(i).Lookup(r.to); prev != nil {
+				return r.errorf(r.prog.fset.Position(prev.Pos()), "it conflicts with %s in the same file", prev)
+			}
+		}
+	}
+	// The references must not be shadowed by another object of the new name.
+	selectors := make(map[*PackageInfo]map[*ast.Ident]bool)
+	for _, ref := range r.refs {
+		if ref.File == nil || r.isSelector(selectors, ref.File.PackageInfo, ref.Ident) {
+			continue
+		}
+		for s := ref.File.PackageInfo.Pkg.Scope().Innermost(ref.Ident.Pos()); s != nil && s != scope; s = s.Parent() {
+			if prev := s.Lookup(r.to); prev != nil {
+				return r.errorf(ref.Position, "the reference would be shadowed by %s", prev)
+			}
+		}
+	}
+	// The references of another object of the new name must not be shadowed by the renamed object.
+	pkg := r.prog.allPackages[r.obj.Pkg()]
+	if pkg == nil {
+		return nil
+	}
+	for ident, used := range pkg.info.Uses {
+		if ident.Name != r.to || used == r.obj || r.isSelector(selectors, pkg, ident) {
+			continue
+		}
+		for s := pkg.Pkg.Scope().Innermost(ident.Pos()); s != nil && s != used.Parent(); s = s.Parent() {
+			if s == scope {
+				return r.errorf(r.prog.fset.Position(ident.Pos()), "it would shadow the reference of %s", used)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSelectable checks the renaming of a method or field.
+func (r *renamer) checkSelectable() error {
+	var owners []types.Type
+	switch obj := r.obj.(type) {
+	case *types.Func:
+		recv := obj.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return nil
+		}
+		owners = []types.Type{recv.Type()}
+	case *types.Var:
+		if !obj.IsField() {
+			return nil
+		}
+		owners = r.prog.fieldOwners(obj)
+	default:
+		return nil
+	}
+	for _, owner := range owners {
+		if err := r.checkSelection(owner); err != nil {
+			return err
+		}
+		named := namedOf(owner)
+		if named == nil {
+			continue
+		}
+		// The types embedding the owner select the new name through it.
+		for _, T := range r.prog.namedTypes() {
+			if T != named && embeds(T, named) {
+				if err := r.checkSelection(T); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	fn, ok := r.obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	named := namedOf(fn.Type().(*types.Signature).Recv().Type())
+	if named == nil {
+		return nil
+	}
+	if iface, ok := named.Underlying().(*types.Interface); ok {
+		// The types implementing the interface would miss the method.
+		for _, T := range r.prog.namedTypes() {
+			if _, isIface := T.Underlying().(*types.Interface); isIface || T.TypeParams().Len() > 0 {
+				continue
+			}
+			if types.Implements(T, iface) || types.Implements(types.NewPointer(T), iface) {
+				return r.errorf(r.prog.fset.Position(T.Obj().Pos()), "%s would not implement %s", T, named)
+			}
+		}
+		return nil
+	}
+	// The type would not implement the interfaces with the method any more.
+	for _, I := range r.prog.namedTypes() {
+		iface, ok := I.Underlying().(*types.Interface)
+		if !ok || I.TypeParams().Len() > 0 {
+			continue
+		}
+		if m, _, _ := types.LookupFieldOrMethod(iface, false, r.obj.Pkg(), r.from); m == nil {
+			continue
+		}
+		if types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface) {
+			return r.errorf(r.prog.fset.Position(I.Obj().Pos()), "%s would not implement %s", named, I)
+		}
+	}
+	return nil
+}
+
+// checkSelection returns an error if the new name is already selectable from the type,
+// which the renamed method or field would conflict with, shadow or be shadowed by.
+func (r *renamer) checkSelection(typ types.Type) error {
+	prev, index, _ := types.LookupFieldOrMethod(typ, true, r.obj.Pkg(), r.to)
+	if prev != nil {
+		return r.errorf(r.prog.fset.Position(prev.Pos()), "it conflicts with %s selected from %s", prev, typ)
+	}
+	if index != nil {
+		return r.errorf(r.prog.fset.Position(r.obj.Pos()), "%s is already ambiguous in %s", r.to, typ)
+	}
+	return nil
+}
+
+// isSelector reports whether the identifier is the selector of a selector expression,
+// which is not resolved in the lexical scopes.
+func (r *renamer) isSelector(cache map[*PackageInfo]map[*ast.Ident]bool, pkg *PackageInfo, ident *ast.Ident) bool {
+	sels, ok := cache[pkg]
+	if !ok {
+		sels = make(map[*ast.Ident]bool)
+		for _, f := range pkg.Files {
+			ast.Inspect(f.File, func(n ast.Node) bool {
+				if sel, ok := n.(*ast.SelectorExpr); ok {
+					sels[sel.Sel] = true
+				}
+				return true
+			})
+		}
+		cache[pkg] = sels
+	}
+	return sels[ident]
+}
+
+func (r *renamer) errorf(pos token.Position, format string, args ...interface{}) error {
+	return fmt.Errorf("aster: can not rename %s to %s: %s: %s", r.from, r.to, pos, fmt.Sprintf(format, args...))
+}
+
+// embeddingFields returns the struct fields embedding the type in all the packages.
+func (prog *Program) embeddingFields(tn *types.TypeName) []*types.Var {
+	var fields []*types.Var
+	for _, pkg := range prog.allPackages {
+		for _, obj := range pkg.info.Defs {
+			if v, ok := obj.(*types.Var); ok && v.Embedded() {
+				if named := namedOf(v.Type()); named != nil && named.Obj() == tn {
+					fields = append(fields, v)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// fieldOwners returns the named types whose underlying struct declares the field,
+// or the struct itself if there is none.
+func (prog *Program) fieldOwners(field *types.Var) []types.Type {
+	pkg := prog.allPackages[field.Pkg()]
+	if pkg == nil {
+		return nil
+	}
+	var owners []types.Type
+	for _, obj := range pkg.info.Defs {
+		if tn, ok := obj.(*types.TypeName); ok && !tn.IsAlias() {
+			if s, ok := tn.Type().Underlying().(*types.Struct); ok && declaresField(s, field) {
+				owners = append(owners, tn.Type())
+			}
+		}
+	}
+	if len(owners) == 0 {
+		for _, tv := range pkg.info.Types {
+			if s, ok := tv.Type.(*types.Struct); ok && declaresField(s, field) {
+				return []types.Type{s}
+			}
+		}
+	}
+	return owners
+}
+
+// declaresField reports whether the field is declared by the struct.
+func declaresField(s *types.Struct, field *types.Var) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if s.Field(i) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// embeds reports whether the struct or interface type of typ embeds the named type, directly or not.
+func embeds(typ types.Type, named *types.Named) bool {
+	seen := make(map[*types.Named]bool)
+	var walk func(typ types.Type) bool
+	walk = func(typ types.Type) bool {
+		var embedded []types.Type
+		switch t := typ.Underlying().(type) {
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				if field := t.Field(i); field.Embedded() {
+					embedded = append(embedded, field.Type())
+				}
+			}
+		case *types.Interface:
+			for i := 0; i < t.NumEmbeddeds(); i++ {
+				embedded = append(embedded, t.EmbeddedType(i))
+			}
+		}
+		for _, typ := range embedded {
+			n := namedOf(typ)
+			if n == nil || seen[n] {
+				continue
+			}
+			if n == named {
+				return true
+			}
+			seen[n] = true
+			if walk(n) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(typ)
+}
+
+// namedTypes returns the package-level named types of all the packages.
+func (prog *Program) namedTypes() []*types.Named {
+	var list []*types.Named
+	for pkg := range prog.allPackages {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			if tn, ok := scope.Lookup(name).(*types.TypeName); ok && !tn.IsAlias() {
+				if named, ok := tn.Type().(*types.Named); ok {
+					list = append(list, named)
+				}
+			}
+		}
+	}
+	return list
+}
+
+// namedOf returns the named type of T or *T, or nil.
+func namedOf(typ types.Type) *types.Named {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, _ := types.Unalias(typ).(*types.Named)
+	if named != nil {
+		named = named.Origin()
+	}
+	return named
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRename(t *testing.T) {
+	var src = `package test
+
+type A struct{ X int }
+
+type S struct{ A }
+
+func F(s S) int {
+	a := A{X: 1}
+	return a.X + s.A.X
+}
+`
+	prog, err := aster.LoadFile("rename.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	a := prog.Lookup(aster.Typ, 0, "A")[0]
+	assert.NoError(t, a.Rename("B"))
+	assert.Equal(t, "B", a.Name())
+	codes, err := prog.Format()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `package test
+
+type B struct{ X int }
+
+type S struct{ B }
+
+func F(s S) int {
+	a := B{X: 1}
+	return a.X + s.B.X
+}
+`, codes["rename.go"])
+	}
+	assert.NoError(t, prog.Recheck())
+	assert.Empty(t, prog.Diagnostics())
+}
+
+func TestRenameConflicts(t *testing.T) {
+	var src = `package test
+
+import "fmt"
+
+var X int
+
+var Y int
+
+func F() int {
+	y := 1
+	fmt.Println(len("x"))
+	return X + y
+}
+
+type I interface{ M() }
+
+type T struct{}
+
+func (T) M() {}
+
+func (T) N() {}
+`
+	prog, err := aster.LoadFile("conflicts.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	x := prog.Lookup(aster.Var, 0, "X")[0]
+	assert.EqualError(t, x.Rename("1x"), `aster: can not rename X to "1x", which is not a valid identifier`)
+	assert.ErrorContains(t, x.Rename("Y"), "in the same scope")
+	assert.ErrorContains(t, x.Rename("fmt"), "in the same file")
+	assert.ErrorContains(t, x.Rename("y"), "would be shadowed by var y int")
+	assert.ErrorContains(t, x.Rename("len"), "would shadow the reference of builtin len")
+	assert.NoError(t, x.Rename("Z"))
+
+	typ := prog.Lookup(aster.Typ, 0, "T")[0]
+	m, n := typ.Method(0), typ.Method(1)
+	assert.Equal(t, "M", m.Name())
+	assert.ErrorContains(t, m.Rename("O"), "test.T would not implement test.I")
+	assert.ErrorContains(t, m.Rename("N"), "it conflicts with func (test.T).N()")
+	im := prog.Lookup(aster.Typ, 0, "I")[0].IfaceExplicitMethod(0)
+	assert.ErrorContains(t, im.Rename("O"), "test.T would not implement test.I")
+	assert.NoError(t, n.Rename("O"))
+}
+
+func TestRenameUnexport(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nfunc A() int { return 1 }\n\nfunc B() int { return A() }\n",
+		"b/b.go": "package b\n\nimport \"example.com/m/a\"\n\nvar B = a.A()\n",
+	})
+	prog, err := aster.NewProgram().SetWorkDir(dir).ImportPatterns("./...").Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	a := prog.Package("example.com/m/a").Lookup(aster.Fun, 0, "A")[0]
+	assert.ErrorContains(t, a.Rename("a"), "it is referenced by package example.com/m/b")
+	assert.NoError(t, a.Rename("C"))
+	codes, err := prog.Format()
+	if assert.NoError(t, err) {
+		for name, code := range codes {
+			assert.NotContains(t, code, "A()", name)
+		}
+	}
+}
+
+func TestRenameFieldConflicts(t *testing.T) {
+	var src = `package test
+
+type E struct{ P int }
+
+func (E) Q() {}
+
+type S struct {
+	E
+	A struct{}
+	B int
+}
+
+func (S) C() {}
+
+type W struct {
+	S
+	D int
+}
+
+type T struct{}
+
+type V struct {
+	T
+	U int
+}
+
+var _ = W{}.A
+`
+	prog, err := aster.LoadFile("fields.go", src)
+	if !assert.NoError(t, err) {
+		return
+	}
+	a := prog.Lookup(aster.Var, 0, "A")[0]
+	assert.ErrorContains(t, a.Rename("B"), "it conflicts with field B int selected from test.S")
+	assert.ErrorContains(t, a.Rename("C"), "it conflicts with func (test.S).C() selected from test.S")
+	// promoted through the embedded E
+	assert.ErrorContains(t, a.Rename("P"), "it conflicts with field P int selected from test.S")
+	assert.ErrorContains(t, a.Rename("Q"), "it conflicts with func (test.E).Q() selected from test.S")
+	// W embedding S has D
+	assert.ErrorContains(t, a.Rename("D"), "it conflicts with field D int selected from test.W")
+	c := prog.Lookup(aster.Typ, 0, "S")[0].Method(0)
+	assert.ErrorContains(t, c.Rename("D"), "it conflicts with field D int selected from test.W")
+	// the embedded field of V would be renamed too
+	typ := prog.Lookup(aster.Typ, 0, "T")[0]
+	assert.ErrorContains(t, typ.Rename("U"), "it conflicts with field U int selected from test.V")
+	assert.NoError(t, a.Rename("F"))
+	codes, err := prog.Format()
+	if assert.NoError(t, err) {
+		assert.Contains(t, codes["fields.go"], "var _ = W{}.F\n")
+	}
+	assert.NoError(t, prog.Recheck())
+	assert.Empty(t, prog.Diagnostics())
+}
+
+func TestRenameUncheckedBodies(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nfunc A() int { return 1 }\n\nfunc B() int { return A() }\n",
+	})
+	prog, err := aster.NewProgram().SetWorkDir(dir).SetLoadMode(aster.LoadDecls).ImportPatterns("./...").Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	a := prog.Package("example.com/m/a").Lookup(aster.Fun, 0, "A")[0]
+	assert.ErrorContains(t, a.Rename("C"), "the function bodies of package example.com/m/a are not type-checked")
+	assert.Equal(t, "A", a.Name())
+}
@@ -17,9 +17,11 @@ package aster
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/andeya/structtag"
 )
@@ -37,8 +39,21 @@ func (fa *facade) structure() *types.Struct {
 	if fa.structFields == nil {
 		numFields := t.NumFields()
 		fa.structFields = make([]*StructField, numFields)
-		for expr, tv := range fa.pkg.info.Types {
-			if tv.Type == t {
+		// The fields of an instantiated generic struct are declared by its origin.
+		declared, info := t, &types.Info{}
+		if fa.pkg != nil {
+			info = &fa.pkg.info
+		}
+		if named, ok := fa.obj.Type().(*types.Named); ok && named.Origin() != named {
+			if origin, ok := named.Origin().Underlying().(*types.Struct); ok {
+				declared = origin
+				if pkg, ok := fa.pkg.prog.allPackages[named.Obj().Pkg()]; ok {
+					info = &pkg.info
+				}
+			}
+		}
+		for expr, tv := range info.Types {
+			if tv.Type == declared {
 				n, ok := expr.(*ast.StructType)
 				if !ok {
 					a, ok := expr.(*ast.CompositeLit)
@@ -51,15 +66,34 @@ func (fa *facade) structure() *types.Struct {
 				}
 				expandFields(n.Fields)
 				for i := 0; i < numFields; i++ {
-					fa.structFields[i] = fa.newStructField(n.Fields.List[i], t.Field(i))
+					fa.structFields[i] = fa.newStructField(n.Fields.List[i], t.Field(i), i)
 				}
 				break
 			}
 		}
+		// The fields without syntax, such as those of a package loaded from export data.
+		for i, field := range fa.structFields {
+			if field == nil {
+				fa.structFields[i] = fa.newStructField(syntheticField(t, i), t.Field(i), i)
+			}
+		}
 	}
 	return t
 }
 
+// syntheticField creates the syntax of the i'th field of the struct.
+func syntheticField(t *types.Struct, i int) *ast.Field {
+	v := t.Field(i)
+	field := &ast.Field{Type: ast.NewIdent(types.TypeString(v.Type(), nil))}
+	if !v.Embedded() {
+		field.Names = []*ast.Ident{ast.NewIdent(v.Name())}
+	}
+	if tag := t.Tag(i); tag != "" {
+		field.Tag = &ast.BasicLit{Kind: token.STRING, Value: "`" + tag + "`"}
+	}
+	return field
+}
+
 // NumFields returns the number of fields in the struct (including blank and embedded fields).
 // NOTE: Panic, if TypKind != Struct
 func (fa *facade) NumFields() int {
@@ -80,15 +114,164 @@ func (fa *facade) Field(i int) *StructField {
 
 // FieldByName returns the struct field with the given name
 // and a boolean indicating if the field was found.
+// The fields promoted from the embedded structs are also considered, see FieldByNameFunc.
 // NOTE: Panic, if TypKind != Struct
 func (fa *facade) FieldByName(name string) (field *StructField, found bool) {
+	return fa.FieldByNameFunc(func(s string) bool { return s == name })
+}
+
+// FieldByIndex returns the nested field corresponding to the index sequence,
+// such as the StructField.Index of the promoted field,
+// and a boolean indicating if the field was found.
+// NOTE: Panic, if TypKind != Struct
+func (fa *facade) FieldByIndex(index []int) (field *StructField, found bool) {
 	fa.structure() // make sure initiated
-	for _, field := range fa.structFields {
-		if field.Name() == name {
-			return field, true
+	fields := fa.structFields
+	for _, i := range index {
+		if i < 0 || i >= len(fields) {
+			return nil, false
 		}
+		field = fields[i]
+		fields = field.promoted()
+	}
+	return field, field != nil
+}
+
+// FieldByNameFunc returns the struct field with a name that satisfies the match function
+// and a boolean indicating if the field was found.
+//
+// As the selectors of Go, the fields promoted from the embedded structs are considered
+// in breadth first order, and it stops at the shallowest depth containing a matched field.
+// If there are multiple matched fields at that depth, they cancel each other out,
+// and FieldByNameFunc returns no match.
+// NOTE: Panic, if TypKind != Struct
+func (fa *facade) FieldByNameFunc(match func(string) bool) (field *StructField, found bool) {
+	fa.walkFields(func(*StructField) bool { return true }, func(level []*StructField) bool {
+		var n int
+		for _, sf := range level {
+			if match(sf.Name()) {
+				field = sf
+				n++
+			}
+		}
+		if n > 1 {
+			field = nil
+		}
+		return n == 0
+	})
+	return field, field != nil
+}
+
+// AllFields returns the effective fields of the struct, including the promoted ones,
+// following the rules of encoding/json:
+//
+//	The unexported fields and the fields tagged `json:"-"` are ignored;
+//	The embedded structs (or pointers to struct) without json name are flattened,
+//	and the others are fields themselves;
+//	The name of a field is its json name, or the field name if untagged;
+//	Among the fields with the same name, the shallowest one is chosen,
+//	then the tagged one, otherwise they are all ignored.
+//
+// The fields are ordered by their index sequence.
+// NOTE: Panic, if TypKind != Struct
+func (fa *facade) AllFields() []*StructField {
+	type candidate struct {
+		field  *StructField
+		tagged bool
+	}
+	var names []string
+	byName := make(map[string][]candidate)
+	fa.walkFields(func(sf *StructField) bool {
+		name, ok := sf.jsonName()
+		return ok && name == ""
+	}, func(level []*StructField) bool {
+		for _, sf := range level {
+			name, ok := sf.jsonName()
+			switch {
+			case !ok:
+				continue
+			case sf.Embedded():
+				isStruct := sf.embeddedStruct() != nil
+				if !sf.Exported() && !isStruct {
+					continue
+				}
+				if name == "" && isStruct {
+					continue // flattened
+				}
+			case !sf.Exported():
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name()
+			}
+			if _, ok := byName[name]; !ok {
+				names = append(names, name)
+			}
+			byName[name] = append(byName[name], candidate{field: sf, tagged: tagged})
+		}
+		return true
+	})
+	var fields []*StructField
+	for _, name := range names {
+		list := byName[name]
+		// The candidates are in breadth first order, so the first ones are the shallowest.
+		depth := len(list[0].field.index)
+		var dominant *StructField
+		var n, tagged int
+		for _, c := range list {
+			if len(c.field.index) > depth {
+				break
+			}
+			n++
+			if c.tagged {
+				tagged++
+				dominant = c.field
+			}
+		}
+		switch {
+		case n == 1:
+			fields = append(fields, list[0].field)
+		case tagged == 1:
+			fields = append(fields, dominant)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		x, y := fields[i].index, fields[j].index
+		for k := 0; k < len(x) && k < len(y); k++ {
+			if x[k] != y[k] {
+				return x[k] < y[k]
+			}
+		}
+		return len(x) < len(y)
+	})
+	return fields
+}
+
+// walkFields walks the fields and the promoted fields in breadth first order, level by level,
+// until fn returns false.
+// The embedded structs for which expand returns true are expanded into the next level,
+// unless they have been expanded at a shallower level.
+func (fa *facade) walkFields(expand func(*StructField) bool, fn func(level []*StructField) bool) {
+	fa.structure() // make sure initiated
+	level := fa.structFields
+	visited := map[types.Type]bool{fa.obj.Type(): true}
+	for len(level) > 0 && fn(level) {
+		var next []*StructField
+		expanded := make(map[types.Type]bool)
+		for _, sf := range level {
+			typ := sf.embeddedStruct()
+			if typ == nil || visited[typ] || !expand(sf) {
+				continue
+			}
+			expanded[typ] = true
+			next = append(next, sf.promoted()...)
+		}
+		for typ := range expanded {
+			visited[typ] = true
+		}
+		level = next
 	}
-	return nil, false
 }
 
 // StructField struct field object.
@@ -96,19 +279,124 @@ type StructField struct {
 	node   *ast.Field
 	obj    *types.Var
 	tags   *Tags
-	facade *facade
+	facade *facade // the struct declaring the field
+	index  []int
 }
 
-func (fa *facade) newStructField(node *ast.Field, obj *types.Var) *StructField {
+func (fa *facade) newStructField(node *ast.Field, obj *types.Var, i int) *StructField {
 	sf := &StructField{
 		node:   node,
 		obj:    obj,
 		tags:   newTags(node),
 		facade: fa,
+		index:  []int{i},
 	}
 	return sf
 }
 
+// Index returns the index sequence for FieldByIndex,
+// which has more than one element for a promoted field.
+func (sf *StructField) Index() []int {
+	return append([]int(nil), sf.index...)
+}
+
+// Type returns the field's type.
+func (sf *StructField) Type() types.Type {
+	return sf.obj.Type()
+}
+
+// TypeFacade returns the facade of the field's type, or of its element type if it is a pointer.
+// NOTE: Returns nil, if the type has no facade, such as a basic or an unnamed type.
+func (sf *StructField) TypeFacade() Facade {
+	typ := sf.obj.Type()
+	if ptr, ok := types.Unalias(typ).(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if fa := sf.facade.getFacadeByTyp(typ); fa != nil {
+		return fa
+	}
+	return nil
+}
+
+// embeddedStruct returns the type of the embedded struct, or of the struct pointed to by the embedded pointer,
+// or nil if the field is not one of them.
+func (sf *StructField) embeddedStruct() types.Type {
+	if !sf.obj.Embedded() {
+		return nil
+	}
+	typ := types.Unalias(sf.obj.Type())
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = types.Unalias(ptr.Elem())
+	}
+	if _, ok := typ.Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	return typ
+}
+
+// promoted returns the fields of the embedded struct, with the index sequences from the outer struct,
+// or nil if the field is not an embedded struct.
+func (sf *StructField) promoted() []*StructField {
+	typ := sf.embeddedStruct()
+	if typ == nil {
+		return nil
+	}
+	t := typ.Underlying().(*types.Struct)
+	fields := make([]*StructField, t.NumFields())
+	fa := sf.facade.getFacadeByTyp(typ)
+	if fa != nil {
+		fa.structure() // make sure initiated
+	}
+	for i := range fields {
+		var field StructField
+		if fa != nil {
+			// The fields of an instantiated generic struct are declared by its origin.
+			field = *fa.structFields[i]
+			field.obj = t.Field(i)
+		} else {
+			// The struct literal without facade, such as the one denoted by an alias.
+			field = *sf.facade.newStructField(syntheticField(t, i), t.Field(i), i)
+		}
+		field.index = append(append(make([]int, 0, len(sf.index)+1), sf.index...), i)
+		fields[i] = &field
+	}
+	return fields
+}
+
+// jsonName returns the name of the json tag, or "" if it is not a valid name as encoding/json requires,
+// and false if the field is ignored by `json:"-"`.
+func (sf *StructField) jsonName() (string, bool) {
+	tag, err := sf.tags.Get("json")
+	if err != nil {
+		return "", true
+	}
+	if tag.Name == "-" && len(tag.Options) == 0 {
+		return "", false
+	}
+	if !isValidJSONName(tag.Name) {
+		return "", true
+	}
+	return tag.Name, true
+}
+
+// isValidJSONName reports whether the name of the json tag is used by encoding/json.
+func isValidJSONName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
 // Name returns the field's name.
 func (sf *StructField) Name() string {
 	return sf.obj.Name()
@@ -158,7 +446,9 @@ func (sf *StructField) SetDoc(text string) {
 	if sf.node.Doc == nil || len(sf.node.Doc.List) == 0 {
 		sf.node.Doc = newCommentGroup()
 		sf.node.Doc.List[0].Slash = sf.node.Pos() - 1
-		sf.facade.file.appendComment(sf.node.Doc)
+		if sf.facade.file != nil {
+			sf.facade.file.appendComment(sf.node.Doc)
+		}
 	}
 	doc := sf.node.Doc.List[0]
 	doc.Text = cleanDoc(text)
@@ -169,7 +459,9 @@ func (sf *StructField) SetComment(text string) {
 	if sf.node.Comment == nil || len(sf.node.Comment.List) == 0 {
 		sf.node.Comment = newCommentGroup()
 		sf.node.Comment.List[0].Slash = sf.node.Pos() + 1
-		sf.facade.file.appendComment(sf.node.Comment)
+		if sf.facade.file != nil {
+			sf.facade.file.appendComment(sf.node.Comment)
+		}
 	}
 	doc := sf.node.Comment.List[0]
 	doc.Text = cleanDoc(text)
@@ -298,7 +590,7 @@ func (s *Tags) Flush() {
 		s.field.Tag = nil
 	} else {
 		if s.field.Tag == nil {
-			s.field.Tag = &ast.BasicLit{}
+			s.field.Tag = &ast.BasicLit{Kind: token.STRING, ValuePos: s.field.Type.End() + 1}
 		}
 		s.field.Tag.Value = "`" + value + "`"
 	}
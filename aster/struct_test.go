@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestStruct(t *testing.T) {
@@ -103,3 +104,120 @@ var S2 = struct{
 		t.Fatal(err)
 	}
 }
+
+func TestPromotedFields(t *testing.T) {
+	var src = `package test
+
+import "time"
+
+type Base struct {
+	ID      int
+	Name    string
+	private int
+}
+
+type Meta struct {
+	Name string
+	Tag  string
+}
+
+type Named struct {
+	Name string ` + "`json:\"Name\"`" + `
+}
+
+type Gen[T any] struct {
+	Value T
+}
+
+type S struct {
+	Base
+	*Meta
+	Gen[string]
+	Self  *S
+	When  time.Time
+	Skip  int ` + "`json:\"-\"`" + `
+	Other Base ` + "`json:\"other\"`" + `
+}
+
+type J struct {
+	Meta
+	Named
+}
+
+type M struct {
+	A int ` + "`json:\"a'b\"`" + `
+	B int ` + "`json:\"A\"`" + `
+}
+
+type L = struct {
+	X int ` + "`json:\"x\"`" + `
+}
+
+type K struct {
+	L
+}
+`
+	prog, err := aster.LoadFile("promoted.go", src)
+	assert.NoError(t, err)
+	s := prog.Lookup(aster.Typ, aster.Struct, "S")[0]
+
+	id, ok := s.FieldByName("ID")
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 0}, id.Index())
+	assert.Equal(t, "int", id.Type().String())
+	byIndex, ok := s.FieldByIndex(id.Index())
+	assert.True(t, ok)
+	assert.Equal(t, "ID", byIndex.Name())
+	_, ok = s.FieldByIndex([]int{3, 0})
+	assert.False(t, ok)
+
+	// Base.Name and Meta.Name are at the same depth.
+	_, ok = s.FieldByName("Name")
+	assert.False(t, ok)
+
+	value, ok := s.FieldByNameFunc(func(name string) bool { return name == "Value" })
+	assert.True(t, ok)
+	assert.Equal(t, "string", value.Type().String())
+	assert.Equal(t, []int{2, 0}, value.Index())
+
+	self, _ := s.FieldByName("Self")
+	assert.Equal(t, "S", self.TypeFacade().Name())
+	when, _ := s.FieldByName("When")
+	assert.Equal(t, "Time", when.TypeFacade().Name())
+	assert.Nil(t, id.TypeFacade())
+
+	var names []string
+	for _, field := range s.AllFields() {
+		names = append(names, field.Name())
+	}
+	assert.Equal(t, []string{"ID", "Tag", "Value", "Self", "When", "Other"}, names)
+
+	// The tagged field dominates.
+	j := prog.Lookup(aster.Typ, aster.Struct, "J")[0]
+	fields := j.AllFields()
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "Tag", fields[0].Name())
+	assert.Equal(t, []int{1, 0}, fields[1].Index())
+
+	// The invalid json name is ignored, so the tagged B dominates A.
+	m := prog.Lookup(aster.Typ, aster.Struct, "M")[0]
+	fields = m.AllFields()
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "B", fields[0].Name())
+	}
+
+	// The struct literal denoted by an alias has no facade.
+	k := prog.Lookup(aster.Typ, aster.Struct, "K")[0]
+	x, ok := k.FieldByName("X")
+	if assert.True(t, ok) {
+		assert.Equal(t, []int{0, 0}, x.Index())
+	}
+	byIndex, ok = k.FieldByIndex([]int{0, 0})
+	if assert.True(t, ok) {
+		assert.Equal(t, "X", byIndex.Name())
+	}
+	fields = k.AllFields()
+	if assert.Len(t, fields, 1) {
+		assert.Equal(t, "X", fields[0].Name())
+	}
+}
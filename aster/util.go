@@ -37,6 +37,48 @@ func cloneBasicLit(b *ast.BasicLit) *ast.BasicLit {
 	}
 }
 
+// clearPos clears the positions of the expression parsed elsewhere,
+// so that the printer places it where it is inserted.
+func clearPos(expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.Ident:
+			x.NamePos = token.NoPos
+		case *ast.BasicLit:
+			x.ValuePos = token.NoPos
+		case *ast.Ellipsis:
+			x.Ellipsis = token.NoPos
+		case *ast.ParenExpr:
+			x.Lparen, x.Rparen = token.NoPos, token.NoPos
+		case *ast.IndexExpr:
+			x.Lbrack, x.Rbrack = token.NoPos, token.NoPos
+		case *ast.IndexListExpr:
+			x.Lbrack, x.Rbrack = token.NoPos, token.NoPos
+		case *ast.StarExpr:
+			x.Star = token.NoPos
+		case *ast.UnaryExpr:
+			x.OpPos = token.NoPos
+		case *ast.BinaryExpr:
+			x.OpPos = token.NoPos
+		case *ast.ArrayType:
+			x.Lbrack = token.NoPos
+		case *ast.StructType:
+			x.Struct = token.NoPos
+		case *ast.FuncType:
+			x.Func = token.NoPos
+		case *ast.InterfaceType:
+			x.Interface = token.NoPos
+		case *ast.MapType:
+			x.Map = token.NoPos
+		case *ast.ChanType:
+			x.Begin, x.Arrow = token.NoPos, token.NoPos
+		case *ast.FieldList:
+			x.Opening, x.Closing = token.NoPos, token.NoPos
+		}
+		return true
+	})
+}
+
 func textOrError(text string, err error) string {
 	if err == nil {
 		return text
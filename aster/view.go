@@ -0,0 +1,451 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// KindError is returned when the TypKind of the facade is not the wanted one.
+type KindError struct {
+	Facade Facade
+	Want   TypKind // the wanted TypKind set
+	Got    TypKind
+}
+
+// Error implements error.
+func (e *KindError) Error() string {
+	return fmt.Sprintf("aster: %s is of TypKind %s, want %s", e.Facade.Name(), e.Got, e.Want)
+}
+
+// CheckTypKind returns a *KindError if the TypKind is not in set, or nil.
+// It is the error-returning alternative to the panics of the kind-specific methods.
+func (fa *facade) CheckTypKind(set TypKind) error {
+	if got := fa.TypKind(); !got.In(set) {
+		return &KindError{Facade: fa, Want: set, Got: got}
+	}
+	return nil
+}
+
+// StructView is the view of a facade whose TypKind is Struct.
+type StructView interface {
+	structViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// NumFields returns the number of fields in the struct (including blank and embedded fields).
+	NumFields() int
+	// Field returns the i'th field, or an error if i is not in the range [0, NumFields()).
+	Field(i int) (*StructField, error)
+	// FieldByName returns the struct field with the given name
+	// and a boolean indicating if the field was found.
+	FieldByName(name string) (*StructField, bool)
+	// FieldByIndex returns the nested field corresponding to the index sequence
+	// and a boolean indicating if the field was found.
+	FieldByIndex(index []int) (*StructField, bool)
+	// FieldByNameFunc returns the struct field with a name that satisfies the match function
+	// and a boolean indicating if the field was found.
+	FieldByNameFunc(match func(string) bool) (*StructField, bool)
+	// AllFields returns the effective fields of the struct, following the rules of encoding/json.
+	AllFields() []*StructField
+}
+
+// FuncView is the view of a facade whose TypKind is Signature.
+type FuncView interface {
+	funcViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// IsMethod returns whether it is a method.
+	IsMethod() bool
+	// Params returns the parameters, or nil.
+	Params() *types.Tuple
+	// Recv returns the receiver if a method, or nil if a function.
+	Recv() *types.Var
+	// Results returns the results, or nil.
+	Results() *types.Tuple
+	// Variadic reports whether the signature is variadic.
+	Variadic() bool
+	// Body returns function body.
+	Body() (string, error)
+	// CoverBody covers function body.
+	CoverBody(body string) error
+}
+
+// InterfaceView is the view of a facade whose TypKind is Interface.
+type InterfaceView interface {
+	interfaceViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Empty returns true if it is the empty interface.
+	Empty() bool
+	// NumEmbeddeds returns the number of embedded types.
+	NumEmbeddeds() int
+	// EmbeddedType returns the i'th embedded type,
+	// or an error if i is not in the range [0, NumEmbeddeds()) or the type has no facade.
+	EmbeddedType(i int) (Facade, error)
+	// NumExplicitMethods returns the number of explicitly declared methods.
+	NumExplicitMethods() int
+	// ExplicitMethod returns the i'th explicitly declared method ordered by their unique Id,
+	// or an error if i is not in the range [0, NumExplicitMethods()).
+	ExplicitMethod(i int) (Facade, error)
+}
+
+// MapView is the view of a facade whose TypKind is Map.
+type MapView interface {
+	mapViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Key returns the key type.
+	Key() types.Type
+	// Elem returns the element type.
+	Elem() types.Type
+}
+
+// SliceView is the view of a facade whose TypKind is Slice.
+type SliceView interface {
+	sliceViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Elem returns the element type.
+	Elem() types.Type
+}
+
+// ArrayView is the view of a facade whose TypKind is Array.
+type ArrayView interface {
+	arrayViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Elem returns the element type.
+	Elem() types.Type
+	// Len returns the length, a negative result indicates an unknown length.
+	Len() int64
+}
+
+// ChanView is the view of a facade whose TypKind is Chan.
+type ChanView interface {
+	chanViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Elem returns the element type.
+	Elem() types.Type
+	// Dir returns the direction of channel.
+	Dir() types.ChanDir
+}
+
+// PointerView is the view of a facade whose TypKind is Pointer.
+type PointerView interface {
+	pointerViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Elem returns the element type.
+	Elem() types.Type
+}
+
+// BasicView is the view of a facade whose TypKind is Basic.
+type BasicView interface {
+	basicViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Info returns information about properties of basic type.
+	Info() types.BasicInfo
+	// Kind returns the kind of basic type.
+	Kind() types.BasicKind
+}
+
+// TypeParamView is the view of a facade whose TypKind is TypeParam.
+type TypeParamView interface {
+	typeParamViewIdentify() // only as identify
+
+	// Facade returns the viewed facade.
+	Facade() Facade
+	// Constraint returns the type constraint.
+	Constraint() types.Type
+	// SetConstraint covers the type constraint expression.
+	SetConstraint(constraint string) error
+}
+
+// baseView is embedded by the views,
+// and its facade has the TypKind checked when the view is created.
+type baseView struct {
+	fa *facade
+}
+
+func (v baseView) Facade() Facade {
+	return v.fa
+}
+
+// elemView is embedded by the views of the types having an element type.
+type elemView struct {
+	baseView
+}
+
+func (v elemView) Elem() types.Type {
+	return v.fa.Elem()
+}
+
+// The views of each TypKind, which only have the methods of their kind.
+type (
+	structView    struct{ baseView }
+	funcView      struct{ baseView }
+	interfaceView struct{ baseView }
+	mapView       struct{ elemView }
+	sliceView     struct{ elemView }
+	arrayView     struct{ elemView }
+	chanView      struct{ elemView }
+	pointerView   struct{ elemView }
+	basicView     struct{ baseView }
+	typeParamView struct{ baseView }
+)
+
+var (
+	_ StructView    = structView{}
+	_ FuncView      = funcView{}
+	_ InterfaceView = interfaceView{}
+	_ MapView       = mapView{}
+	_ SliceView     = sliceView{}
+	_ ArrayView     = arrayView{}
+	_ ChanView      = chanView{}
+	_ PointerView   = pointerView{}
+	_ BasicView     = basicView{}
+	_ TypeParamView = typeParamView{}
+)
+
+func (structView) structViewIdentify() {}
+
+func (funcView) funcViewIdentify() {}
+
+func (interfaceView) interfaceViewIdentify() {}
+
+func (mapView) mapViewIdentify() {}
+
+func (sliceView) sliceViewIdentify() {}
+
+func (arrayView) arrayViewIdentify() {}
+
+func (chanView) chanViewIdentify() {}
+
+func (pointerView) pointerViewIdentify() {}
+
+func (basicView) basicViewIdentify() {}
+
+func (typeParamView) typeParamViewIdentify() {}
+
+// AsStruct returns the struct view, if TypKind is Struct.
+func (fa *facade) AsStruct() (StructView, bool) {
+	if fa.CheckTypKind(Struct) != nil {
+		return nil, false
+	}
+	return structView{baseView{fa}}, true
+}
+
+// AsFunc returns the function view, if TypKind is Signature.
+func (fa *facade) AsFunc() (FuncView, bool) {
+	if fa.CheckTypKind(Signature) != nil {
+		return nil, false
+	}
+	return funcView{baseView{fa}}, true
+}
+
+// AsInterface returns the interface view, if TypKind is Interface.
+func (fa *facade) AsInterface() (InterfaceView, bool) {
+	if fa.CheckTypKind(Interface) != nil {
+		return nil, false
+	}
+	return interfaceView{baseView{fa}}, true
+}
+
+// AsMap returns the map view, if TypKind is Map.
+func (fa *facade) AsMap() (MapView, bool) {
+	if fa.CheckTypKind(Map) != nil {
+		return nil, false
+	}
+	return mapView{elemView{baseView{fa}}}, true
+}
+
+// AsSlice returns the slice view, if TypKind is Slice.
+func (fa *facade) AsSlice() (SliceView, bool) {
+	if fa.CheckTypKind(Slice) != nil {
+		return nil, false
+	}
+	return sliceView{elemView{baseView{fa}}}, true
+}
+
+// AsArray returns the array view, if TypKind is Array.
+func (fa *facade) AsArray() (ArrayView, bool) {
+	if fa.CheckTypKind(Array) != nil {
+		return nil, false
+	}
+	return arrayView{elemView{baseView{fa}}}, true
+}
+
+// AsChan returns the channel view, if TypKind is Chan.
+func (fa *facade) AsChan() (ChanView, bool) {
+	if fa.CheckTypKind(Chan) != nil {
+		return nil, false
+	}
+	return chanView{elemView{baseView{fa}}}, true
+}
+
+// AsPointer returns the pointer view, if TypKind is Pointer.
+func (fa *facade) AsPointer() (PointerView, bool) {
+	if fa.CheckTypKind(Pointer) != nil {
+		return nil, false
+	}
+	return pointerView{elemView{baseView{fa}}}, true
+}
+
+// AsBasic returns the basic view, if TypKind is Basic.
+func (fa *facade) AsBasic() (BasicView, bool) {
+	if fa.CheckTypKind(Basic) != nil {
+		return nil, false
+	}
+	return basicView{baseView{fa}}, true
+}
+
+// AsTypeParam returns the type parameter view, if TypKind is TypeParam.
+func (fa *facade) AsTypeParam() (TypeParamView, bool) {
+	if fa.CheckTypKind(TypeParam) != nil {
+		return nil, false
+	}
+	return typeParamView{baseView{fa}}, true
+}
+
+func (v structView) NumFields() int {
+	return v.fa.NumFields()
+}
+
+func (v structView) Field(i int) (*StructField, error) {
+	if n := v.fa.NumFields(); i < 0 || i >= n {
+		return nil, fmt.Errorf("aster: field index %d out of range [0, %d)", i, n)
+	}
+	return v.fa.Field(i), nil
+}
+
+func (v structView) FieldByName(name string) (*StructField, bool) {
+	return v.fa.FieldByName(name)
+}
+
+func (v structView) FieldByIndex(index []int) (*StructField, bool) {
+	return v.fa.FieldByIndex(index)
+}
+
+func (v structView) FieldByNameFunc(match func(string) bool) (*StructField, bool) {
+	return v.fa.FieldByNameFunc(match)
+}
+
+func (v structView) AllFields() []*StructField {
+	return v.fa.AllFields()
+}
+
+func (v funcView) IsMethod() bool {
+	return v.fa.IsMethod()
+}
+
+func (v funcView) Params() *types.Tuple {
+	return v.fa.Params()
+}
+
+func (v funcView) Recv() *types.Var {
+	return v.fa.Recv()
+}
+
+func (v funcView) Results() *types.Tuple {
+	return v.fa.Results()
+}
+
+func (v funcView) Variadic() bool {
+	return v.fa.Variadic()
+}
+
+func (v funcView) Body() (string, error) {
+	return v.fa.Body()
+}
+
+func (v funcView) CoverBody(body string) error {
+	return v.fa.CoverBody(body)
+}
+
+func (v interfaceView) Empty() bool {
+	return v.fa.IfaceEmpty()
+}
+
+func (v interfaceView) NumEmbeddeds() int {
+	return v.fa.IfaceNumEmbeddeds()
+}
+
+func (v interfaceView) EmbeddedType(i int) (Facade, error) {
+	iface := v.fa.iface()
+	if n := iface.NumEmbeddeds(); i < 0 || i >= n {
+		return nil, fmt.Errorf("aster: embedded type index %d out of range [0, %d)", i, n)
+	}
+	t := iface.EmbeddedType(i)
+	if fa := v.fa.getFacadeByTyp(t); fa != nil {
+		return fa, nil
+	}
+	return nil, fmt.Errorf("aster: embedded type %s has no facade", t)
+}
+
+func (v interfaceView) NumExplicitMethods() int {
+	return v.fa.IfaceNumExplicitMethods()
+}
+
+func (v interfaceView) ExplicitMethod(i int) (Facade, error) {
+	iface := v.fa.iface()
+	if n := iface.NumExplicitMethods(); i < 0 || i >= n {
+		return nil, fmt.Errorf("aster: explicit method index %d out of range [0, %d)", i, n)
+	}
+	fn := iface.ExplicitMethod(i)
+	if fa := v.fa.getFacadeByObj(fn); fa != nil {
+		return fa, nil
+	}
+	return nil, fmt.Errorf("aster: explicit method %s has no facade", fn.Name())
+}
+
+func (v mapView) Key() types.Type {
+	return v.fa.Key()
+}
+
+func (v arrayView) Len() int64 {
+	return v.fa.Len()
+}
+
+func (v chanView) Dir() types.ChanDir {
+	return v.fa.ChanDir()
+}
+
+func (v basicView) Info() types.BasicInfo {
+	return v.fa.BasicInfo()
+}
+
+func (v basicView) Kind() types.BasicKind {
+	return v.fa.BasicKind()
+}
+
+func (v typeParamView) Constraint() types.Type {
+	return v.fa.Constraint()
+}
+
+func (v typeParamView) SetConstraint(constraint string) error {
+	return v.fa.SetConstraint(constraint)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 AndeyaLee. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aster_test
+
+import (
+	"errors"
+	"go/types"
+	"testing"
+
+	"github.com/andeya/aster/aster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViews(t *testing.T) {
+	var src = `package test
+import "fmt"
+type S struct{ A int }
+type M map[string]int
+type A [3]bool
+type C chan<- int
+type I interface{
+	fmt.Stringer
+	Print()
+}
+type G[T any] struct{ v T }
+func F(a int, b ...string) (err error) { return nil }
+`
+	prog, err := aster.LoadFile("view.go", src)
+	assert.NoError(t, err)
+	lookup := func(name string) aster.Facade {
+		list := prog.Lookup(aster.AnyObjKind, aster.AnyTypKind, name)
+		assert.Len(t, list, 1, name)
+		return list[0]
+	}
+
+	s := lookup("S")
+	sv, ok := s.AsStruct()
+	assert.True(t, ok)
+	assert.Same(t, s, sv.Facade())
+	assert.Equal(t, 1, sv.NumFields())
+	f, err := sv.Field(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "A", f.Name())
+	_, err = sv.Field(1)
+	assert.Error(t, err)
+	_, ok = s.AsMap()
+	assert.False(t, ok)
+
+	err = s.CheckTypKind(aster.Map | aster.Slice)
+	var kindErr *aster.KindError
+	assert.True(t, errors.As(err, &kindErr))
+	assert.Equal(t, aster.Struct, kindErr.Got)
+	assert.NoError(t, s.CheckTypKind(aster.Struct))
+
+	mv, ok := lookup("M").AsMap()
+	assert.True(t, ok)
+	assert.Equal(t, "string", mv.Key().String())
+	assert.Equal(t, "int", mv.Elem().String())
+
+	av, ok := lookup("A").AsArray()
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), av.Len())
+
+	cv, ok := lookup("C").AsChan()
+	assert.True(t, ok)
+	assert.Equal(t, types.SendOnly, cv.Dir())
+
+	iv, ok := lookup("I").AsInterface()
+	assert.True(t, ok)
+	assert.False(t, iv.Empty())
+	assert.Equal(t, 1, iv.NumEmbeddeds())
+	emb, err := iv.EmbeddedType(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "Stringer", emb.Name())
+	m, err := iv.ExplicitMethod(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "Print", m.Name())
+	_, err = iv.ExplicitMethod(1)
+	assert.Error(t, err)
+
+	fv, ok := lookup("F").AsFunc()
+	assert.True(t, ok)
+	assert.False(t, fv.IsMethod())
+	assert.True(t, fv.Variadic())
+	assert.Equal(t, 2, fv.Params().Len())
+	assert.Equal(t, 1, fv.Results().Len())
+
+	tv, ok := lookup("T").AsTypeParam()
+	assert.True(t, ok)
+	assert.Equal(t, "any", tv.Constraint().String())
+
+	_, ok = lookup("F").AsBasic()
+	assert.False(t, ok)
+
+	// Each view only has the methods of its kind.
+	var v interface{} = sv
+	_, ok = v.(aster.FuncView)
+	assert.False(t, ok)
+	_, ok = v.(aster.MapView)
+	assert.False(t, ok)
+	v = fv
+	_, ok = v.(aster.StructView)
+	assert.False(t, ok)
+	v = av
+	_, ok = v.(aster.SliceView)
+	assert.False(t, ok)
+	_, ok = v.(aster.PointerView)
+	assert.False(t, ok)
+	_, ok = v.(aster.ArrayView)
+	assert.True(t, ok)
+}
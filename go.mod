@@ -1,9 +1,16 @@
 module github.com/andeya/aster
 
-go 1.12
+go 1.23
 
 require (
 	github.com/andeya/goutil v0.0.0-20220626152529-9b7868da7b6d
 	github.com/andeya/structtag v1.2.0
 	github.com/stretchr/testify v1.7.5
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/henrylee2cn/ameda v1.4.10 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -98,11 +98,16 @@ func Read(in io.Reader, fset *token.FileSet, imports map[string]*types.Package,
 
 // Write writes encoded type information for the specified package to out.
 // The FileSet provides file position information for named objects.
+//
+// The indexed export format is written, so that the generic types are supported.
 func Write(out io.Writer, fset *token.FileSet, pkg *types.Package) error {
-	b, err := gcimporter.BExportData(fset, pkg)
+	b, err := gcimporter.IExportData(fset, pkg)
 	if err != nil {
 		return err
 	}
+	if _, err = io.WriteString(out, "i"); err != nil {
+		return err
+	}
 	_, err = out.Write(b)
 	return err
 }
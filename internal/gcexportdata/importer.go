@@ -6,8 +6,10 @@ package gcexportdata
 
 import (
 	"fmt"
+	goimporter "go/importer"
 	"go/token"
 	"go/types"
+	"io"
 	"os"
 )
 
@@ -70,3 +72,16 @@ func (imp importer) ImportFrom(importPath, srcDir string, mode types.ImportMode)
 
 	return Read(r, imp.fset, imp.imports, path)
 }
+
+// NewLookupImporter returns a new instance of the types.Importer interface
+// that reads type information from the export data files written by the
+// gc compiler of the running Go toolchain, which are located by lookup
+// (see go/importer.Lookup).
+//
+// Unlike Read, it understands the unified export data format written
+// by Go 1.20 and later, since it is backed by go/importer.
+// The packages are shared among the files read by the same Importer,
+// and the Importer is not safe for concurrent use.
+func NewLookupImporter(fset *token.FileSet, lookup func(path string) (io.ReadCloser, error)) types.Importer {
+	return goimporter.ForCompiler(fset, "gc", lookup)
+}
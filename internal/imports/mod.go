@@ -315,6 +315,12 @@ var (
 	moduleStr  = []byte("module")
 )
 
+// ModulePath returns the module path from the gomod file text,
+// or an empty string if it cannot find one.
+func ModulePath(mod []byte) string {
+	return modulePath(mod)
+}
+
 // modulePath returns the module path from the gomod file text.
 // If it cannot find a module path, it returns an empty string.
 // It is tolerant of unrelated problems in the go.mod file.
@@ -7,6 +7,7 @@ package loader
 // See doc.go for package documentation and implementation notes.
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -129,6 +130,49 @@ type Config struct {
 	//
 	// It must be safe to call concurrently from multiple goroutines.
 	AfterTypeCheck func(info *PackageInfo, files []*File)
+
+	// BeforeTypeCheck is called immediately before a list of files
+	// is type-checked.
+	//
+	// It must be safe to call concurrently from multiple goroutines.
+	BeforeTypeCheck func(info *PackageInfo, files []*File)
+
+	// Context specifies the context for the load operation.
+	// If the context is cancelled, the loader stops locating and
+	// type-checking packages, and Load returns the context's error.
+	// If Context is nil, the load cannot be cancelled.
+	Context context.Context
+
+	// Parallelism limits the number of packages type-checked concurrently.
+	// If zero or negative, the number is unlimited.
+	Parallelism int
+
+	// ImportFromExportData, if non-nil, imports the packages which are
+	// not in ImportPkgs, such as from the compiler export data,
+	// instead of loading them from source.
+	// On failure, the package is loaded from source.
+	//
+	// It must be safe to call concurrently from multiple goroutines.
+	ImportFromExportData func(path, dir string) (*types.Package, error)
+
+	// PackageCache, if non-nil, caches the packages which are not
+	// in ImportPkgs, instead of loading them from source every time.
+	PackageCache PackageCache
+}
+
+// A PackageCache caches the type-checked packages, such as in
+// export data files.
+//
+// Its methods must be safe to call concurrently from multiple goroutines.
+type PackageCache interface {
+	// Get returns the cached package, or nil if not found.
+	// It is called after the dependencies of the package are loaded,
+	// and imports contains the packages the cached one may refer to,
+	// keyed by path.
+	Get(bp *build.Package, imports map[string]*types.Package) *types.Package
+
+	// Put stores the package type-checked without errors.
+	Put(bp *build.Package, pkg *types.Package)
 }
 
 // A PkgSpec specifies a non-importable package to be created by Load.
@@ -141,6 +185,11 @@ type PkgSpec struct {
 	Path      string   // package path ("" => use package declaration)
 	Files     []*File  // ASTs of already-parsed files
 	Filenames []string // names of files to be parsed
+
+	// Importable reports whether Path is the import path of the package,
+	// so that the other packages importing Path use this package,
+	// instead of loading it again from its directory.
+	Importable bool
 }
 
 // A Program is a Go program loaded from source as specified by a Config.
@@ -309,6 +358,12 @@ func (conf *Config) CreateFromFiles(path string, files ...*File) {
 	conf.CreatePkgs = append(conf.CreatePkgs, PkgSpec{Path: path, Files: files})
 }
 
+// CreateImportableFromFiles is the same as CreateFromFiles,
+// but path is the import path of the package, by which the other packages import it.
+func (conf *Config) CreateImportableFromFiles(path string, files ...*File) {
+	conf.CreatePkgs = append(conf.CreatePkgs, PkgSpec{Path: path, Files: files, Importable: true})
+}
+
 // ImportWithTests is a convenience function that adds path to
 // ImportPkgs, the set of initial source packages located relative to
 // $GOPATH.  The package will be augmented by any *_test.go files in
@@ -402,6 +457,9 @@ type importer struct {
 	importedMu sync.Mutex             // guards imported
 	imported   map[string]*importInfo // all imported packages (incl. failures) by import path
 
+	// created is the importable packages of Config.CreatePkgs by import path.
+	created map[string]*PkgSpec
+
 	// import dependency graph: graph[x][y] => x imports y
 	//
 	// Since non-importable packages cannot be cyclic, we ignore
@@ -409,6 +467,10 @@ type importer struct {
 	// packages.  Nodes are identified by their import paths.
 	graphMu sync.Mutex
 	graph   map[string]map[string]bool
+
+	// checkSem limits the number of packages type-checked concurrently,
+	// nil if unlimited.
+	checkSem chan struct{}
 }
 
 type findpkgKey struct {
@@ -482,6 +544,10 @@ func (conf *Config) Load() (*Program, error) {
 		}
 	}
 
+	if conf.Context == nil {
+		conf.Context = context.Background()
+	}
+
 	// Install default FindPackage hook using go/build logic.
 	if conf.FindPackage == nil {
 		conf.FindPackage = (*build.Context).Import
@@ -499,9 +565,18 @@ func (conf *Config) Load() (*Program, error) {
 		prog:     prog,
 		findpkg:  make(map[findpkgKey]*findpkgValue),
 		imported: make(map[string]*importInfo),
+		created:  make(map[string]*PkgSpec),
 		start:    time.Now(),
 		graph:    make(map[string]map[string]bool),
 	}
+	for i := range conf.CreatePkgs {
+		if cp := &conf.CreatePkgs[i]; cp.Importable && cp.Path != "" {
+			imp.created[cp.Path] = cp
+		}
+	}
+	if conf.Parallelism > 0 {
+		imp.checkSem = make(chan struct{}, conf.Parallelism)
+	}
 
 	// -- loading proper (concurrent phase) --------------------------------
 
@@ -595,6 +670,14 @@ func (conf *Config) Load() (*Program, error) {
 
 	// Create packages specified by conf.CreatePkgs.
 	for _, cp := range conf.CreatePkgs {
+		if cp.Importable && cp.Path != "" {
+			// It may have been loaded as the dependency of another package.
+			bp, _ := imp.findPackage(cp.Path, conf.Cwd, 0)
+			ii := imp.startLoad(bp)
+			ii.awaitCompletion()
+			prog.Created = append(prog.Created, ii.info)
+			continue
+		}
 		files, errs := parseFiles(conf.fset(), conf.build(), nil, conf.Cwd, cp.Filenames, conf.ParserMode)
 		files = append(files, cp.Files...)
 
@@ -621,6 +704,10 @@ func (conf *Config) Load() (*Program, error) {
 		createPkg(bp.ImportPath+"_test", bp.Dir, files, errs)
 	}
 
+	if err := conf.Context.Err(); err != nil {
+		return nil, err
+	}
+
 	// -- finishing up (sequential) ----------------------------------------
 
 	if len(prog.Imported)+len(prog.Created) == 0 {
@@ -814,6 +901,9 @@ func (imp *importer) doImport(from *PackageInfo, to string) (*types.Package, err
 func (imp *importer) findPackage(importPath, fromDir string, mode build.ImportMode) (*build.Package, error) {
 	// We use a non-blocking duplicate-suppressing cache (gopl.io §9.7)
 	// to avoid holding the lock around FindPackage.
+	if cp := imp.created[importPath]; cp != nil {
+		return &build.Package{ImportPath: importPath, Dir: imp.createdDir(cp)}, nil
+	}
 	key := findpkgKey{importPath, fromDir, mode}
 	imp.findpkgMu.Lock()
 	v, ok := imp.findpkg[key]
@@ -829,9 +919,13 @@ func (imp *importer) findPackage(importPath, fromDir string, mode build.ImportMo
 		imp.findpkg[key] = v
 		imp.findpkgMu.Unlock()
 
-		ioLimit <- true
-		v.bp, v.err = imp.conf.FindPackage(imp.conf.build(), importPath, fromDir, mode)
-		<-ioLimit
+		if err := imp.conf.Context.Err(); err != nil {
+			v.err = err
+		} else {
+			ioLimit <- true
+			v.bp, v.err = imp.conf.FindPackage(imp.conf.build(), importPath, fromDir, mode)
+			<-ioLimit
+		}
 
 		if _, ok := v.err.(*build.NoGoError); ok {
 			v.err = nil // empty directory is not an error
@@ -960,6 +1054,15 @@ func (imp *importer) startLoad(bp *build.Package) *importInfo {
 // load implements package loading by parsing Go source files
 // located by go/build.
 func (imp *importer) load(bp *build.Package) *PackageInfo {
+	if cp := imp.created[bp.ImportPath]; cp != nil {
+		return imp.loadCreated(bp, cp)
+	}
+	if info := imp.loadFromExportData(bp); info != nil {
+		return info
+	}
+	if info := imp.loadFromCache(bp); info != nil {
+		return info
+	}
 	info := imp.newPackageInfo(bp.ImportPath, bp.Dir)
 	info.Importable = true
 	files, errs := imp.conf.parsePackageFiles(bp, 'g')
@@ -969,6 +1072,29 @@ func (imp *importer) load(bp *build.Package) *PackageInfo {
 
 	imp.addFiles(info, files, true)
 
+	if imp.cacheable(bp) && len(info.Errors) == 0 {
+		imp.conf.PackageCache.Put(bp, info.Pkg)
+	}
+
+	imp.progMu.Lock()
+	imp.prog.importMap[bp.ImportPath] = info.Pkg
+	imp.progMu.Unlock()
+
+	return info
+}
+
+// loadCreated type-checks the importable package of Config.CreatePkgs.
+func (imp *importer) loadCreated(bp *build.Package, cp *PkgSpec) *PackageInfo {
+	info := imp.newPackageInfo(bp.ImportPath, bp.Dir)
+	info.Importable = true
+	files, errs := parseFiles(imp.conf.fset(), imp.conf.build(), nil, imp.conf.Cwd, cp.Filenames, imp.conf.ParserMode)
+	files = append(files, cp.Files...)
+	for _, err := range errs {
+		info.appendError(err)
+	}
+
+	imp.addFiles(info, files, true)
+
 	imp.progMu.Lock()
 	imp.prog.importMap[bp.ImportPath] = info.Pkg
 	imp.progMu.Unlock()
@@ -976,6 +1102,109 @@ func (imp *importer) load(bp *build.Package) *PackageInfo {
 	return info
 }
 
+// createdDir returns the directory of the package of Config.CreatePkgs,
+// which is the one that contains the first file.
+func (imp *importer) createdDir(cp *PkgSpec) string {
+	if len(cp.Files) > 0 && cp.Files[0].Pos().IsValid() {
+		return filepath.Dir(imp.conf.fset().File(cp.Files[0].Pos()).Name())
+	}
+	if len(cp.Filenames) > 0 {
+		if filepath.IsAbs(cp.Filenames[0]) {
+			return filepath.Dir(cp.Filenames[0])
+		}
+		return filepath.Join(imp.conf.Cwd, filepath.Dir(cp.Filenames[0]))
+	}
+	return imp.conf.Cwd
+}
+
+// loadFromExportData imports the non-initial package by ImportFromExportData,
+// and returns nil if it is not applicable or fails.
+func (imp *importer) loadFromExportData(bp *build.Package) *PackageInfo {
+	if imp.conf.ImportFromExportData == nil {
+		return nil
+	}
+	if _, initial := imp.conf.ImportPkgs[bp.ImportPath]; initial {
+		return nil
+	}
+	pkg, err := imp.conf.ImportFromExportData(bp.ImportPath, bp.Dir)
+	if err != nil || !pkg.Complete() {
+		return nil
+	}
+	info := &PackageInfo{
+		Pkg:        pkg,
+		Importable: true,
+		dir:        bp.Dir,
+	}
+	imp.progMu.Lock()
+	imp.prog.AllPackages[pkg] = info
+	imp.prog.importMap[bp.ImportPath] = pkg
+	imp.progMu.Unlock()
+	return info
+}
+
+// cacheable reports whether the package may be cached by PackageCache.
+// The cgo packages are not, since their processed files have other imports,
+// nor is unsafe, which is known to the type checker.
+func (imp *importer) cacheable(bp *build.Package) bool {
+	if imp.conf.PackageCache == nil || len(bp.CgoFiles) > 0 || bp.ImportPath == "unsafe" {
+		return false
+	}
+	_, initial := imp.conf.ImportPkgs[bp.ImportPath]
+	return !initial
+}
+
+// loadFromCache loads the dependencies of the package, then gets the
+// package from PackageCache, and returns nil if it is not applicable or found.
+//
+// The dependencies are loaded first, so that the cached package refers to
+// the same packages as the ones loaded from source.
+func (imp *importer) loadFromCache(bp *build.Package) *PackageInfo {
+	if !imp.cacheable(bp) {
+		return nil
+	}
+	imports := make(map[string]bool, len(bp.Imports))
+	for _, path := range bp.Imports {
+		if path != "C" {
+			imports[path] = true
+		}
+	}
+	infos, errs := imp.importAll(bp.ImportPath, bp.Dir, imports, 0)
+	if len(errs) > 0 {
+		return nil
+	}
+
+	// Build the view of the packages which the cached one may refer to,
+	// i.e. its transitive dependencies.
+	view := make(map[string]*types.Package)
+	var visit func(pkg *types.Package)
+	visit = func(pkg *types.Package) {
+		if _, ok := view[pkg.Path()]; ok {
+			return
+		}
+		view[pkg.Path()] = pkg
+		for _, dep := range pkg.Imports() {
+			visit(dep)
+		}
+	}
+	for _, info := range infos {
+		visit(info.Pkg)
+	}
+	pkg := imp.conf.PackageCache.Get(bp, view)
+	if pkg == nil {
+		return nil
+	}
+	info := &PackageInfo{
+		Pkg:        pkg,
+		Importable: true,
+		dir:        bp.Dir,
+	}
+	imp.progMu.Lock()
+	imp.prog.AllPackages[pkg] = info
+	imp.prog.importMap[bp.ImportPath] = pkg
+	imp.progMu.Unlock()
+	return info
+}
+
 // addFiles adds and type-checks the specified files to info, loading
 // their dependencies if needed.  The order of files determines the
 // package initialization order.  It may be called multiple times on the
@@ -1004,7 +1233,12 @@ func (imp *importer) addFiles(info *PackageInfo, files []*File, cycleCheck bool)
 		if len(files) > 0 {
 			panic(`"unsafe" package contains unexpected files`)
 		}
+	} else if err := imp.acquireCheck(); err != nil {
+		info.appendError(err)
 	} else {
+		if imp.conf.BeforeTypeCheck != nil {
+			imp.conf.BeforeTypeCheck(info, files)
+		}
 		// Ignore the returned (first) error since we
 		// already collect them all in the PackageInfo.
 		fs := make([]*ast.File, len(files))
@@ -1013,6 +1247,7 @@ func (imp *importer) addFiles(info *PackageInfo, files []*File, cycleCheck bool)
 		}
 		info.checker.Files(fs)
 		info.Files = append(info.Files, files...)
+		imp.releaseCheck()
 	}
 
 	if imp.conf.AfterTypeCheck != nil {
@@ -1025,6 +1260,31 @@ func (imp *importer) addFiles(info *PackageInfo, files []*File, cycleCheck bool)
 	}
 }
 
+// acquireCheck waits for a type-checking slot,
+// or returns the context's error if the load is cancelled.
+func (imp *importer) acquireCheck() error {
+	ctx := imp.conf.Context
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if imp.checkSem == nil {
+		return nil
+	}
+	select {
+	case imp.checkSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseCheck releases the type-checking slot acquired by acquireCheck.
+func (imp *importer) releaseCheck() {
+	if imp.checkSem != nil {
+		<-imp.checkSem
+	}
+}
+
 func (imp *importer) newPackageInfo(path, dir string) *PackageInfo {
 	var pkg *types.Package
 	if path == "unsafe" {
@@ -1041,6 +1301,7 @@ func (imp *importer) newPackageInfo(path, dir string) *PackageInfo {
 			Implicits:  make(map[ast.Node]types.Object),
 			Scopes:     make(map[ast.Node]*types.Scope),
 			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Instances:  make(map[*ast.Ident]types.Instance),
 		},
 		errorFunc: imp.conf.TypeChecker.Error,
 		dir:       dir,
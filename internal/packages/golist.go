@@ -141,8 +141,10 @@ extractQueries:
 	if sizeserr != nil {
 		return nil, sizeserr
 	}
-	// types.SizesFor always returns nil or a *types.StdSizes
+	// types.SizesFor used to always return nil or a *types.StdSizes,
+	// newer releases return an unexported implementation for "gc".
 	response.dr.Sizes, _ = sizes.(*types.StdSizes)
+	response.dr.sizes = sizes
 
 	var containsCandidates []string
 
@@ -711,7 +713,7 @@ func golistargs(cfg *Config, words []string) []string {
 		fmt.Sprintf("-compiled=%t", cfg.Mode&(NeedCompiledGoFiles|NeedSyntax|NeedTypesInfo|NeedTypesSizes) != 0),
 		fmt.Sprintf("-test=%t", cfg.Tests),
 		fmt.Sprintf("-export=%t", usesExportData(cfg)),
-		fmt.Sprintf("-deps=%t", cfg.Mode&NeedDeps != 0),
+		fmt.Sprintf("-deps=%t", cfg.Mode&NeedDeps != 0 || cfg.ListDeps),
 		// go list doesn't let you pass -test and -find together,
 		// probably because you'd just get the TestMain.
 		fmt.Sprintf("-find=%t", !cfg.Tests && cfg.Mode&findFlags == 0),
@@ -15,6 +15,7 @@ import (
 	"go/scanner"
 	"go/token"
 	"go/types"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -117,6 +118,26 @@ type Config struct {
 	// If Context is nil, the load cannot be cancelled.
 	Context context.Context
 
+	// TypeCheckFuncBodies, if non-nil, determines whether to type-check
+	// the function bodies of the package, instead of the rule depending on Mode.
+	// initial reports whether the package is matched by a pattern.
+	TypeCheckFuncBodies func(pkg *Package, initial bool) bool
+
+	// Parallelism limits the number of packages type-checked concurrently.
+	// If zero or negative, the number is unlimited.
+	Parallelism int
+
+	// BeforeTypeCheck and AfterTypeCheck, if non-nil, are called
+	// immediately before and after a package is parsed and type-checked.
+	//
+	// They must be safe to call concurrently from multiple goroutines.
+	BeforeTypeCheck func(pkg *Package)
+	AfterTypeCheck  func(pkg *Package)
+
+	// ListDeps lists the dependencies even without NeedDeps,
+	// so that the imports are loaded from their export data.
+	ListDeps bool
+
 	// Dir is the directory in which to run the build system's query tool
 	// that provides information about the packages.
 	// If Dir is empty, the tool is run in the current directory.
@@ -198,6 +219,10 @@ type driverResponse struct {
 	// Imports will be connected and then type and syntax information added in a
 	// later pass (see refine).
 	Packages []*Package
+
+	// sizes is the types.Sizes reported by the go list driver,
+	// which is not necessarily a *types.StdSizes.
+	sizes types.Sizes
 }
 
 // Load loads and returns the Go packages named by the given patterns.
@@ -220,7 +245,11 @@ func Load(cfg *Config, patterns ...string) ([]*Package, error) {
 	if err != nil {
 		return nil, err
 	}
-	l.sizes = response.Sizes
+	if response.sizes != nil {
+		l.sizes = response.sizes
+	} else if response.Sizes != nil {
+		l.sizes = response.Sizes
+	}
 	return l.refine(response.Roots, response.Packages...)
 }
 
@@ -300,6 +329,9 @@ type Package struct {
 
 	// TypesSizes provides the effective size function for types in TypesInfo.
 	TypesSizes types.Sizes
+
+	// TypeErrors contains the subset of errors produced during type checking.
+	TypeErrors []types.Error
 }
 
 // An Error describes a problem with a package's metadata, syntax, or types.
@@ -421,7 +453,9 @@ type loader struct {
 	sizes        types.Sizes
 	parseCache   map[string]*parseValue
 	parseCacheMu sync.Mutex
-	exportMu     sync.Mutex // enforces mutual exclusion of exportdata operations
+	exportMu     sync.Mutex     // enforces mutual exclusion of exportdata operations
+	exportImp    types.Importer // reads the export data, guarded by exportMu
+	checkSem     chan struct{}  // limits the packages type-checked concurrently, nil if unlimited
 
 	// TODO(matloob): Add an implied mode here and use that instead of mode.
 	// Implied mode would contain all the fields we need the data for so we can
@@ -452,6 +486,9 @@ func newLoader(cfg *Config) *loader {
 	if ld.Context == nil {
 		ld.Context = context.Background()
 	}
+	if ld.Parallelism > 0 {
+		ld.checkSem = make(chan struct{}, ld.Parallelism)
+	}
 	if ld.Dir == "" {
 		if dir, err := os.Getwd(); err == nil {
 			ld.Dir = dir
@@ -493,7 +530,7 @@ func (ld *loader) refine(roots []string, list ...*Package) ([]*Package, error) {
 		lpkg := &loaderPackage{
 			Package:   pkg,
 			needtypes: (ld.Mode&(NeedTypes|NeedTypesInfo) != 0 && rootIndex < 0) || rootIndex >= 0,
-			needsrc: (ld.Mode&(NeedSyntax|NeedTypesInfo) != 0 && rootIndex < 0) || rootIndex >= 0 ||
+			needsrc: (ld.Mode&(NeedSyntax|NeedTypesInfo) != 0 && ld.Mode&NeedDeps != 0 && rootIndex < 0) || rootIndex >= 0 ||
 				len(ld.Overlay) > 0 || // Overlays can invalidate export data. TODO(matloob): make this check fine-grained based on dependencies on overlaid files
 				pkg.ExportFile == "" && pkg.PkgPath != "unsafe",
 		}
@@ -715,10 +752,27 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		return
 	}
 	if !lpkg.needsrc {
-		ld.loadFromExportData(lpkg)
+		if _, err := ld.loadFromExportData(lpkg); err != nil {
+			lpkg.Errors = append(lpkg.Errors, Error{Pos: "-", Msg: err.Error(), Kind: UnknownError})
+			lpkg.Types.MarkComplete() // importers must not wait for it
+		}
 		return // not a source package, don't get syntax trees
 	}
 
+	if err := ld.acquireCheck(); err != nil {
+		lpkg.Errors = append(lpkg.Errors, Error{Pos: "-", Msg: err.Error(), Kind: UnknownError})
+		lpkg.IllTyped = true
+		lpkg.Types.MarkComplete() // importers must not wait for it
+		return
+	}
+	defer ld.releaseCheck()
+	if ld.BeforeTypeCheck != nil {
+		ld.BeforeTypeCheck(lpkg.Package)
+	}
+	if ld.AfterTypeCheck != nil {
+		defer ld.AfterTypeCheck(lpkg.Package)
+	}
+
 	appendError := func(err error) {
 		// Convert various error types into the one true Error.
 		var errs []Error
@@ -747,6 +801,7 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 
 		case types.Error:
 			// from type checker
+			lpkg.TypeErrors = append(lpkg.TypeErrors, err)
 			errs = append(errs, Error{
 				Pos:  err.Fset.Position(err.Pos).String(),
 				Msg:  err.Msg,
@@ -782,6 +837,7 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		Implicits:  make(map[ast.Node]types.Object),
 		Scopes:     make(map[ast.Node]*types.Scope),
 		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
 	}
 	lpkg.TypesSizes = ld.sizes
 
@@ -810,6 +866,11 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		panic("unreachable")
 	})
 
+	ignoreFuncBodies := (ld.Mode&(NeedDeps|NeedTypesInfo) == 0) && !lpkg.initial
+	if ld.TypeCheckFuncBodies != nil {
+		ignoreFuncBodies = !ld.TypeCheckFuncBodies(lpkg.Package, lpkg.initial)
+	}
+
 	// type-check
 	tc := &types.Config{
 		Importer: importer,
@@ -817,7 +878,7 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		// Type-check bodies of functions only in non-initial packages.
 		// Example: for import graph A->B->C and initial packages {A,C},
 		// we can ignore function bodies in B.
-		IgnoreFuncBodies: (ld.Mode&(NeedDeps|NeedTypesInfo) == 0) && !lpkg.initial,
+		IgnoreFuncBodies: ignoreFuncBodies,
 
 		Error: appendError,
 		Sizes: ld.sizes,
@@ -860,6 +921,47 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 	lpkg.IllTyped = illTyped
 }
 
+// openExportFile opens the export data file of the package path.
+func (ld *loader) openExportFile(path string) (io.ReadCloser, error) {
+	var exportFile string
+	for _, lpkg := range ld.pkgs {
+		if lpkg.PkgPath == path && lpkg.ExportFile != "" {
+			exportFile = lpkg.ExportFile
+			if lpkg.ID == path {
+				break // prefer the non-test variant
+			}
+		}
+	}
+	if exportFile == "" {
+		return nil, fmt.Errorf("no export data file for %s", path)
+	}
+	return os.Open(exportFile)
+}
+
+// acquireCheck waits for a type-checking slot,
+// or returns the context's error if the load is cancelled.
+func (ld *loader) acquireCheck() error {
+	if err := ld.Context.Err(); err != nil {
+		return err
+	}
+	if ld.checkSem == nil {
+		return nil
+	}
+	select {
+	case ld.checkSem <- struct{}{}:
+		return nil
+	case <-ld.Context.Done():
+		return ld.Context.Err()
+	}
+}
+
+// releaseCheck releases the type-checking slot acquired by acquireCheck.
+func (ld *loader) releaseCheck() {
+	if ld.checkSem != nil {
+		<-ld.checkSem
+	}
+}
+
 // An importFunc is an implementation of the single-method
 // types.Importer interface based on a function value.
 type importerFunc func(path string) (*types.Package, error)
@@ -982,11 +1084,10 @@ func (ld *loader) loadFromExportData(lpkg *loaderPackage) (*types.Package, error
 		log.Fatalf("internal error: Package %s has no PkgPath", lpkg)
 	}
 
-	// Because gcexportdata.Read has the potential to create or
-	// modify the types.Package for each node in the transitive
-	// closure of dependencies of lpkg, all exportdata operations
-	// must be sequential. (Finer-grained locking would require
-	// changes to the gcexportdata API.)
+	// Because the export data importer has the potential to create
+	// the types.Package for each node in the transitive closure of
+	// dependencies of lpkg, all exportdata operations must be sequential.
+	// (Finer-grained locking would require changes to the gcexportdata API.)
 	//
 	// The exportMu lock guards the Package.Pkg field and the
 	// types.Package it points to, for each Package in the graph.
@@ -1007,11 +1108,6 @@ func (ld *loader) loadFromExportData(lpkg *loaderPackage) (*types.Package, error
 		// Errors while building export data will have been printed to stderr.
 		return nil, fmt.Errorf("no export data file")
 	}
-	f, err := os.Open(lpkg.ExportFile)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
 
 	// Read gc export data.
 	//
@@ -1019,57 +1115,17 @@ func (ld *loader) loadFromExportData(lpkg *loaderPackage) (*types.Package, error
 	// underlying workspaces use the gc toolchain. (Even build
 	// systems that support gccgo don't use it for workspace
 	// queries.)
-	r, err := gcexportdata.NewReader(f)
-	if err != nil {
-		return nil, fmt.Errorf("reading %s: %v", lpkg.ExportFile, err)
-	}
-
-	// Build the view.
-	//
-	// The gcexportdata machinery has no concept of package ID.
-	// It identifies packages by their PkgPath, which although not
-	// globally unique is unique within the scope of one invocation
-	// of the linker, type-checker, or gcexportdata.
-	//
-	// So, we must build a PkgPath-keyed view of the global
-	// (conceptually ID-keyed) cache of packages and pass it to
-	// gcexportdata. The view must contain every existing
-	// package that might possibly be mentioned by the
-	// current package---its transitive closure.
-	//
-	// In loadPackage, we unconditionally create a types.Package for
-	// each dependency so that export data loading does not
-	// create new ones.
 	//
-	// TODO(adonovan): it would be simpler and more efficient
-	// if the export data machinery invoked a callback to
-	// get-or-create a package instead of a map.
-	//
-	view := make(map[string]*types.Package) // view seen by gcexportdata
-	seen := make(map[*loaderPackage]bool)   // all visited packages
-	var visit func(pkgs map[string]*Package)
-	visit = func(pkgs map[string]*Package) {
-		for _, p := range pkgs {
-			lpkg := ld.pkgs[p.ID]
-			if !seen[lpkg] {
-				seen[lpkg] = true
-				view[lpkg.PkgPath] = lpkg.Types
-				visit(lpkg.Imports)
-			}
-		}
+	// The export data is read by the importer of the running toolchain,
+	// which understands its format and shares the packages mentioned
+	// by the export data files, so that no view needs to be built.
+	if ld.exportImp == nil {
+		ld.exportImp = gcexportdata.NewLookupImporter(ld.Fset, ld.openExportFile)
 	}
-	visit(lpkg.Imports)
-
-	viewLen := len(view) + 1 // adding the self package
-	// Parse the export data.
-	// (May modify incomplete packages in view but not create new ones.)
-	tpkg, err := gcexportdata.Read(r, ld.Fset, view, lpkg.PkgPath)
+	tpkg, err := ld.exportImp.Import(lpkg.PkgPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading %s: %v", lpkg.ExportFile, err)
 	}
-	if viewLen != len(view) {
-		log.Fatalf("Unexpected package creation during export data loading")
-	}
 
 	lpkg.Types = tpkg
 	lpkg.IllTyped = false